@@ -0,0 +1,301 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/cardinality"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/continuity"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/derived"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/exporter"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/httpclient"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/lineoutput"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/otlp"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/pushgateway"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/relabel"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/remotewrite"
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+// Config is the optional configuration file of the exporter
+type Config struct {
+	// Metrics extends or overrides the built-in metric catalog
+	Metrics []MetricConfig `yaml:"metrics"`
+	// DerivedMetrics are gauges computed from the metrics of syslog-ng, e.g. rates and ratios
+	DerivedMetrics DerivedMetricsConfig `yaml:"derived_metrics"`
+	// Continuity keeps the counters of syslog-ng continuous across restarts and reloads
+	Continuity continuity.Config `yaml:"continuity"`
+	// MetricRelabelConfigs are applied to the metrics of syslog-ng, like the metric_relabel_configs of Prometheus
+	MetricRelabelConfigs []relabel.Config `yaml:"metric_relabel_configs"`
+	// Redaction hides sensitive information in the label values of syslog-ng
+	Redaction []RedactionConfig `yaml:"redaction"`
+	// ExternalLabels are attached to every series served by the exporter
+	ExternalLabels ExternalLabelsConfig `yaml:"external_labels"`
+	// RemoteWrite pushes the metrics to a Prometheus remote write endpoint
+	RemoteWrite remotewrite.Config `yaml:"remote_write"`
+	// OTLP exports the metrics to an OpenTelemetry collector
+	OTLP otlp.Config `yaml:"otlp"`
+	// Graphite pushes the metrics to a Graphite server in the plaintext protocol
+	Graphite lineoutput.Config `yaml:"graphite"`
+	// StatsD pushes the metrics to a StatsD or DogStatsD server
+	StatsD lineoutput.Config `yaml:"statsd"`
+	// Pushgateway pushes the metrics to a Prometheus Pushgateway periodically and on shutdown
+	Pushgateway pushgateway.Config `yaml:"pushgateway"`
+	// Cardinality limits the number of series exported after relabeling
+	Cardinality cardinality.Config `yaml:"cardinality"`
+	// Modules are named selections of the series served on /metrics?module=<name>
+	Modules map[string]ModuleConfig `yaml:"modules"`
+	// Instances are the syslog-ng instances queried instead of the one at -socket.path
+	Instances InstancesConfig `yaml:"instances"`
+	// Probe enables the /probe endpoint serving the metrics of the syslog-ng control endpoint in its target parameter
+	Probe ProbeConfig `yaml:"probe"`
+}
+
+type MetricConfig struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+	Help string `yaml:"help"`
+	Unit string `yaml:"unit"`
+}
+
+// DerivedMetricsConfig configures the derived metrics
+type DerivedMetricsConfig struct {
+	// Builtin enables derived.BuiltinRules
+	Builtin bool             `yaml:"builtin"`
+	Rules   []derived.Config `yaml:"rules"`
+}
+
+// RedactionConfig redacts the values of the labels with one of the actions
+type RedactionConfig struct {
+	Labels []string `yaml:"labels"`
+	// Action is one of replace, hmac, truncate or strip_userinfo
+	Action string `yaml:"action"`
+	// Regex and Replacement are the parameters of replace
+	Regex       string `yaml:"regex"`
+	Replacement string `yaml:"replacement"`
+	// HMACKeyFile or HMACKeyEnv is the source of the key of hmac
+	HMACKeyFile string `yaml:"hmac_key_file"`
+	HMACKeyEnv  string `yaml:"hmac_key_env"`
+	// Length is the maximum length of the value for truncate, and the length of the hash for hmac
+	Length int `yaml:"length"`
+}
+
+// ModuleConfig selects the series of syslog-ng served by a module with PromQL series selectors
+type ModuleConfig struct {
+	// Match selects the series matching any of the selectors (default: every series)
+	Match []string `yaml:"match"`
+	// Exclude drops the series matching any of the selectors
+	Exclude []string `yaml:"exclude"`
+}
+
+// InstancesConfig configures the syslog-ng instances of a multi-instance exporter
+type InstancesConfig struct {
+	// Label is the name of the label holding the name of the instance (default: exporter.DefaultInstanceLabel)
+	Label   string           `yaml:"label"`
+	Sockets []InstanceConfig `yaml:"sockets"`
+}
+
+// InstanceConfig is a syslog-ng instance and its control socket
+type InstanceConfig struct {
+	Name       string `yaml:"name"`
+	SocketPath string `yaml:"socket_path"`
+	// Timeout is the timeout of querying the instance (default: -service.timeout)
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// ProbeConfig configures the /probe endpoint
+type ProbeConfig struct {
+	// AllowedTargets are regular expressions matching the whole target URL, /probe is disabled without them
+	AllowedTargets []string `yaml:"allowed_targets"`
+	// Timeout is the timeout of a probe (default: -service.timeout)
+	Timeout time.Duration `yaml:"timeout"`
+	// TLSConfig is the TLS configuration of the tls:// targets
+	TLSConfig httpclient.TLSConfig `yaml:"tls_config"`
+}
+
+// ExternalLabelsConfig configures the labels attached to every series served by the exporter
+type ExternalLabelsConfig struct {
+	Labels []ExternalLabelConfig `yaml:"labels"`
+	// OnCollision is the policy of handling series that already have the label: rename, overwrite or keep
+	OnCollision string `yaml:"on_collision"`
+}
+
+// ExternalLabelConfig is an external label, its value is set by exactly one of Value, Env and File
+type ExternalLabelConfig struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+	// Env is the name of the environment variable holding the value
+	Env string `yaml:"env"`
+	// File is the path of the file holding the value, e.g. a Kubernetes downward API volume file
+	File string `yaml:"file"`
+}
+
+func loadConfig(path string) (cfg Config, err error) {
+	if path == "" {
+		return
+	}
+
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	err = yaml.Unmarshal(dat, &cfg)
+	return
+}
+
+func (cfg Config) MetricCatalog() (*syslogngctl.MetricCatalog, error) {
+	var metadata []syslogngctl.MetricMetadata
+	for _, m := range cfg.Metrics {
+		if m.Name == "" {
+			return nil, fmt.Errorf("metric without name in metrics catalog")
+		}
+		typ := io_prometheus_client.MetricType_UNTYPED // type by name, only set help and unit
+		if m.Type != "" {
+			var err error
+			if typ, err = syslogngctl.ParseMetricType(m.Type); err != nil {
+				return nil, fmt.Errorf("metric %q: %w", m.Name, err)
+			}
+		}
+		if m.Unit != "" && !strings.HasSuffix(strings.TrimSuffix(m.Name, "_total"), "_"+m.Unit) {
+			return nil, fmt.Errorf("metric %q: unit %q must be a suffix of the name", m.Name, m.Unit)
+		}
+		metadata = append(metadata, syslogngctl.MetricMetadata{
+			Name: m.Name,
+			Type: typ,
+			Help: m.Help,
+			Unit: m.Unit,
+		})
+	}
+	return syslogngctl.NewMetricCatalog(metadata...), nil
+}
+
+// LabelRedactions compiles the redaction rules of the configuration
+func (cfg Config) LabelRedactions() ([]syslogngctl.LabelRedaction, error) {
+	var redactions []syslogngctl.LabelRedaction
+	for i, r := range cfg.Redaction {
+		if len(r.Labels) == 0 {
+			return nil, fmt.Errorf("redaction rule #%d: no labels", i+1)
+		}
+		var redactor syslogngctl.Redactor
+		switch r.Action {
+		case "replace":
+			re, err := regexp.Compile(r.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("redaction rule #%d: invalid regex: %w", i+1, err)
+			}
+			redactor = syslogngctl.RegexRedactor(re, r.Replacement)
+		case "hmac":
+			key, err := r.hmacKey()
+			if err != nil {
+				return nil, fmt.Errorf("redaction rule #%d: %w", i+1, err)
+			}
+			redactor = syslogngctl.HMACRedactor(key, r.Length)
+		case "truncate":
+			if r.Length <= 0 {
+				return nil, fmt.Errorf("redaction rule #%d: truncate requires a positive length", i+1)
+			}
+			redactor = syslogngctl.TruncateRedactor(r.Length)
+		case "strip_userinfo":
+			redactor = syslogngctl.URLUserinfoRedactor()
+		default:
+			return nil, fmt.Errorf("redaction rule #%d: unknown action %q, use replace, hmac, truncate or strip_userinfo", i+1, r.Action)
+		}
+		redactions = append(redactions, syslogngctl.LabelRedaction{Labels: r.Labels, Redact: redactor})
+	}
+	return redactions, nil
+}
+
+func (r RedactionConfig) hmacKey() ([]byte, error) {
+	var key []byte
+	switch {
+	case r.HMACKeyFile != "":
+		dat, err := os.ReadFile(r.HMACKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key = bytes.TrimSpace(dat)
+	case r.HMACKeyEnv != "":
+		key = []byte(os.Getenv(r.HMACKeyEnv))
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("hmac requires a non-empty key from hmac_key_file or hmac_key_env")
+	}
+	return key, nil
+}
+
+// ExporterModules compiles the modules of the configuration
+func (cfg Config) ExporterModules() (map[string]*exporter.Selection, error) {
+	modules := make(map[string]*exporter.Selection, len(cfg.Modules))
+	for name, m := range cfg.Modules {
+		if len(m.Match) == 0 && len(m.Exclude) == 0 {
+			return nil, fmt.Errorf("module %q: no match or exclude selectors", name)
+		}
+		selection, err := exporter.NewSelection(m.Match, m.Exclude)
+		if err != nil {
+			return nil, fmt.Errorf("module %q: %w", name, err)
+		}
+		modules[name] = selection
+	}
+	return modules, nil
+}
+
+// Resolve returns the external labels with their values read from the environment and files
+func (c ExternalLabelsConfig) Resolve() (map[string]string, error) {
+	labels := make(map[string]string, len(c.Labels))
+	for _, l := range c.Labels {
+		if err := exporter.ValidateExternalLabelName(l.Name); err != nil {
+			return nil, err
+		}
+		if _, found := labels[l.Name]; found {
+			return nil, fmt.Errorf("duplicate external label %q", l.Name)
+		}
+		value, err := l.value()
+		if err != nil {
+			return nil, fmt.Errorf("external label %q: %w", l.Name, err)
+		}
+		labels[l.Name] = value
+	}
+	return labels, nil
+}
+
+func (l ExternalLabelConfig) value() (string, error) {
+	switch {
+	case l.Env != "" && l.File == "" && l.Value == "":
+		value, found := os.LookupEnv(l.Env)
+		if !found {
+			return "", fmt.Errorf("environment variable %s is not set", l.Env)
+		}
+		return value, nil
+	case l.File != "" && l.Env == "" && l.Value == "":
+		dat, err := os.ReadFile(l.File)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(dat)), nil
+	case l.Env == "" && l.File == "":
+		return l.Value, nil
+	default:
+		return "", fmt.Errorf("only one of value, env and file can be set")
+	}
+}
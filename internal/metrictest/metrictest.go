@@ -0,0 +1,88 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrictest has the helpers of the tests building metric families from the text exposition format.
+package metrictest
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+// Parse parses the metric families of the text exposition format in the order they appear in the text
+func Parse(t testing.TB, text string) []*io_prometheus_client.MetricFamily {
+	t.Helper()
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	mfs, err := parser.TextToMetricFamilies(strings.NewReader(text))
+	require.NoError(t, err)
+
+	res := make([]*io_prometheus_client.MetricFamily, 0, len(mfs))
+	for line := range strings.Lines(text) {
+		var name string
+		if fields := strings.Fields(line); len(fields) >= 3 && fields[0] == "#" {
+			name = fields[2]
+		} else if i := strings.IndexAny(line, "{ "); i > 0 {
+			name = line[:i]
+		}
+		mf := mfs[name]
+		for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+			if base, found := strings.CutSuffix(name, suffix); mf == nil && found {
+				mf = mfs[base]
+			}
+		}
+		if mf != nil && !slices.Contains(res, mf) {
+			res = append(res, mf)
+		}
+	}
+	return res
+}
+
+// Text formats the metric families in the text exposition format in their order
+func Text(mfs []*io_prometheus_client.MetricFamily) string {
+	var buf strings.Builder
+	for _, mf := range mfs {
+		_, _ = expfmt.MetricFamilyToText(&buf, mf)
+	}
+	return buf.String()
+}
+
+// Samples returns the sample lines of the metric families, except the excluded ones, in the text exposition format
+// ordered by name and labels
+func Samples(mfs []*io_prometheus_client.MetricFamily, exclude ...string) string {
+	var buf strings.Builder
+	for _, mf := range mfs {
+		if !slices.Contains(exclude, mf.GetName()) {
+			_, _ = expfmt.MetricFamilyToText(&buf, mf)
+		}
+	}
+	return SortLines(buf.String())
+}
+
+// SortLines orders the lines of the text and drops the comments
+func SortLines(text string) string {
+	var lines []string
+	for line := range strings.Lines(text) {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	slices.Sort(lines)
+	return strings.Join(lines, "")
+}
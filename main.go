@@ -15,29 +15,51 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
-	"github.com/prometheus/common/expfmt"
+	io_prometheus_client "github.com/prometheus/client_model/go"
 
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/cardinality"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/continuity"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/derived"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/exporter"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/lineoutput"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/otlp"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/pushgateway"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/relabel"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/remotewrite"
 	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
 )
 
 const (
-	DEFAULT_TIMEOUT_SYSLOG = time.Second * 5
+	DEFAULT_TIMEOUT_SYSLOG = exporter.DefaultTimeout
 	DEFAULT_SERVICE_PORT   = "9577"
 	DEFAULT_SOCKET_ADDR    = "/var/run/syslog-ng/syslog-ng.ctl"
-	license                = "Apache License, Version 2.0"
+
+	DEFAULT_DELAY_BUCKETS        = "0.1,0.5,1,2.5,5,10,30,60,120,300"
+	DEFAULT_DELAY_SUMMARY_WINDOW = 10 * time.Minute
+	REMOTE_WRITE_SCRAPER         = "remote-write"
+	OTLP_SCRAPER                 = "otlp"
+	TEXTFILE_SCRAPER             = "textfile"
+	GRAPHITE_SCRAPER             = "graphite"
+	STATSD_SCRAPER               = "statsd"
+	PUSHGATEWAY_SCRAPER          = "pushgateway"
+	JSON_SCRAPER                 = "json"
+	DEFAULT_TEXTFILE_INTERVAL    = 30 * time.Second
+	license                      = "Apache License, Version 2.0"
 )
 
 // Version should be set build-time, see Makefile
@@ -45,9 +67,47 @@ var Version = "dev"
 
 type RunArgs struct {
 	SocketAddr     string
+	Instances      []string
+	InstanceLabel  string
 	ServicePort    string
 	ServiceAddress string
 	RequestTimeout string
+	WithLegacy     bool
+	Lenient        bool
+	ConfigFile     string
+	BadGateway     bool
+	SeparateSelf   bool
+	ScraperID      string
+	ScraperTTL     string
+	ExternalLabels []string
+	LabelCollision string
+	DerivedBuiltin bool
+	StateFile      string
+
+	RemoteWriteURL      string
+	RemoteWriteInterval string
+	OTLPEndpoint        string
+	OTLPProtocol        string
+	OTLPInterval        string
+	GraphiteAddress     string
+	StatsDAddress       string
+	PushgatewayURL      string
+	PushgatewayJob      string
+	PushgatewayGrouping []string
+	PushgatewayDelete   bool
+	TextfileOutput      string
+	TextfileInterval    string
+	Once                bool
+	JSON                bool
+	JSONFilter          string
+
+	SnapshotInterval     string
+	SnapshotMaxStaleness string
+
+	DelayPollInterval  string
+	DelayBuckets       string
+	DelaySummaryWindow string
+	DelaySeriesTTL     string
 }
 
 func envOrDef(envName string, def string) (res string) {
@@ -58,83 +118,526 @@ func envOrDef(envName string, def string) (res string) {
 	return
 }
 
+func envBoolOrDef(envName string, def bool) bool {
+	if res, err := strconv.ParseBool(os.Getenv(envName)); err == nil {
+		return res
+	}
+	return def
+}
+
+// envListFlag defines a repeatable flag appending to list. The comma-separated value of the environment variable is
+// used if the flag is not given.
+func envListFlag(list *[]string, name, envName, usage string) {
+	if value := os.Getenv(envName); value != "" {
+		*list = strings.Split(value, ",")
+	}
+	fromEnv := len(*list) > 0
+	flag.Func(name, usage, func(value string) error {
+		if fromEnv {
+			*list, fromEnv = nil, false
+		}
+		*list = append(*list, value)
+		return nil
+	})
+}
+
 func main() {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	slog.SetDefault(logger)
 
 	runArgs := RunArgs{}
 
-	logger.Info("starting axosyslog-metrics-exporter", "version", Version, "license", license)
-
 	flag.StringVar(&runArgs.SocketAddr, "socket.path", envOrDef("CONTROL_SOCKET", DEFAULT_SOCKET_ADDR), "syslog-ng control socket path")
+	envListFlag(&runArgs.Instances, "instance", "INSTANCES", "syslog-ng instance in name=socket-path format to query instead of -socket.path, can be repeated (env: comma-separated INSTANCES if not given, overwrites instances.sockets of the configuration file)")
+	flag.StringVar(&runArgs.InstanceLabel, "instance.label", envOrDef("INSTANCE_LABEL", ""), "label holding the name of the syslog-ng instance of every series when querying several instances (default: instances.label of the configuration file, or syslogng)")
 	flag.StringVar(&runArgs.ServicePort, "service.port", envOrDef("SERVICE_PORT", DEFAULT_SERVICE_PORT), "service bind port")
 	flag.StringVar(&runArgs.ServiceAddress, "service.address", envOrDef("SERVICE_ADDRESS", ""), "service bind address in [host]:port format (overwrites service.port)")
 	flag.StringVar(&runArgs.RequestTimeout, "service.timeout", envOrDef("SERVICE_TIMEOUT", DEFAULT_TIMEOUT_SYSLOG.String()), "request timeout")
+	flag.StringVar(&runArgs.ConfigFile, "config.file", envOrDef("CONFIG_FILE", ""), "path of the optional configuration file")
+	flag.StringVar(&runArgs.StateFile, "continuity.state-file", envOrDef("CONTINUITY_STATE_FILE", ""), "path of the state file enabling the lifetime counters that survive the restarts of syslog-ng and the exporter (overwrites continuity.state_file of the configuration file)")
+	flag.StringVar(&runArgs.RemoteWriteURL, "remote-write.url", envOrDef("REMOTE_WRITE_URL", ""), "URL of the Prometheus remote write endpoint to push the metrics to (overwrites remote_write.url of the configuration file)")
+	flag.StringVar(&runArgs.RemoteWriteInterval, "remote-write.interval", envOrDef("REMOTE_WRITE_INTERVAL", ""), "interval of pushing the metrics to the remote write endpoint (default: remote_write.interval of the configuration file, or 30s)")
+	flag.StringVar(&runArgs.GraphiteAddress, "graphite.address", envOrDef("GRAPHITE_ADDRESS", ""), "host:port of the Graphite server to push the metrics to in the plaintext protocol (overwrites graphite.address of the configuration file)")
+	flag.StringVar(&runArgs.StatsDAddress, "statsd.address", envOrDef("STATSD_ADDRESS", ""), "host:port of the StatsD server to push the metrics to (overwrites statsd.address of the configuration file)")
+	flag.StringVar(&runArgs.OTLPEndpoint, "otlp.endpoint", envOrDef("OTLP_ENDPOINT", ""), "URL of the OpenTelemetry collector to export the metrics to (overwrites otlp.endpoint of the configuration file)")
+	flag.StringVar(&runArgs.OTLPProtocol, "otlp.protocol", envOrDef("OTLP_PROTOCOL", ""), "OTLP protocol: http/protobuf or grpc (default: otlp.protocol of the configuration file, or http/protobuf)")
+	flag.StringVar(&runArgs.OTLPInterval, "otlp.interval", envOrDef("OTLP_INTERVAL", ""), "interval of exporting the metrics to the OpenTelemetry collector (default: otlp.interval of the configuration file, or 30s)")
+	flag.StringVar(&runArgs.TextfileOutput, "textfile.output", envOrDef("TEXTFILE_OUTPUT", ""), "path of the *.prom file to write the metrics to for the textfile collector of node_exporter, instead of serving them over HTTP")
+	flag.StringVar(&runArgs.TextfileInterval, "textfile.interval", envOrDef("TEXTFILE_INTERVAL", DEFAULT_TEXTFILE_INTERVAL.String()), "interval of writing the metrics to -textfile.output")
+	flag.StringVar(&runArgs.ScraperID, "scraper.identity", envOrDef("SCRAPER_IDENTITY", exporter.DefaultScraperIdentity), "how scrapers are told apart to deliver every event delay sample to each of them: remote-addr, header:<name> or query:<name>")
+	flag.StringVar(&runArgs.ScraperTTL, "scraper.ttl", envOrDef("SCRAPER_TTL", syslogngctl.DefaultScraperTTL.String()), "time after which inactive scrapers are forgotten")
+	flag.StringVar(&runArgs.SnapshotInterval, "snapshot.interval", envOrDef("SNAPSHOT_INTERVAL", "0s"), "interval of polling syslog-ng in the background and serving the last snapshot to every scraper (0 queries syslog-ng on each scrape)")
+	flag.StringVar(&runArgs.SnapshotMaxStaleness, "snapshot.max-staleness", envOrDef("SNAPSHOT_MAX_STALENESS", "0s"), "age after which the snapshot is refreshed on demand (0 means twice the snapshot interval)")
+	flag.StringVar(&runArgs.DelayPollInterval, "delay.poll-interval", envOrDef("DELAY_POLL_INTERVAL", "0s"), "interval of polling event delay samples for the syslogng_output_event_delay_seconds histogram and summary (0 disables them)")
+	flag.StringVar(&runArgs.DelayBuckets, "delay.buckets", envOrDef("DELAY_BUCKETS", DEFAULT_DELAY_BUCKETS), "comma-separated upper bounds of the event delay histogram buckets in seconds")
+	flag.StringVar(&runArgs.DelaySummaryWindow, "delay.summary-window", envOrDef("DELAY_SUMMARY_WINDOW", DEFAULT_DELAY_SUMMARY_WINDOW.String()), "time window of the event delay summary quantiles")
+	flag.StringVar(&runArgs.DelaySeriesTTL, "delay.series-ttl", envOrDef("DELAY_SERIES_TTL", exporter.DefaultDelaySeriesTTL.String()), "time after which the event delay aggregates of a destination without new samples are forgotten")
+	envListFlag(&runArgs.ExternalLabels, "label", "EXTERNAL_LABELS", "external label in name=value format attached to every served series, can be repeated (env: comma-separated EXTERNAL_LABELS if not given)")
+	flag.StringVar(&runArgs.LabelCollision, "label.collision", envOrDef("LABEL_COLLISION", ""), "handling of series that already have an external label: rename (to exported_<name>), overwrite or keep (default: on_collision of the configuration file, or rename)")
+	envListFlag(&runArgs.PushgatewayGrouping, "pushgateway.grouping", "PUSHGATEWAY_GROUPING", "grouping label in name=value format of the pushed group besides job, can be repeated (env: comma-separated PUSHGATEWAY_GROUPING if not given)")
+	flag.StringVar(&runArgs.PushgatewayURL, "pushgateway.url", envOrDef("PUSHGATEWAY_URL", ""), "URL of the Pushgateway to push the metrics to periodically and on shutdown (overwrites pushgateway.url of the configuration file)")
+	flag.StringVar(&runArgs.PushgatewayJob, "pushgateway.job", envOrDef("PUSHGATEWAY_JOB", ""), "job label of the group pushed to the Pushgateway (default: pushgateway.job of the configuration file, or axosyslog)")
+	flag.BoolVar(&runArgs.PushgatewayDelete, "pushgateway.delete-on-shutdown", envBoolOrDef("PUSHGATEWAY_DELETE_ON_SHUTDOWN", false), "delete the group from the Pushgateway after the final push on shutdown (also enabled by pushgateway.delete_on_shutdown of the configuration file)")
+	flag.BoolVar(&runArgs.JSON, "json", envBoolOrDef("JSON", false), "print the metrics to the standard output once in the schema of /api/v1/metrics and exit, with the status codes of -once (logs go to the standard error)")
+	flag.StringVar(&runArgs.JSONFilter, "json.filter", envOrDef("JSON_FILTER", ""), "filter of -json in the query string format of /api/v1/metrics, e.g. name=syslogng_output_.*&label=id=d_network#0")
+	flag.BoolVar(&runArgs.Once, "once", envBoolOrDef("ONCE", false), "write the metrics to -textfile.output once and exit: with status 0 on success, 1 if writing failed, 2 if querying syslog-ng failed (the file is written anyway)")
+	flag.BoolVar(&runArgs.DerivedBuiltin, "derived.builtin", envBoolOrDef("DERIVED_BUILTIN", false), "export the built-in derived metrics, e.g. syslogng_output_drop_ratio (also enabled by derived_metrics.builtin of the configuration file)")
+	flag.BoolVar(&runArgs.SeparateSelf, "exporter-metrics.separate", envBoolOrDef("EXPORTER_METRICS_SEPARATE", false), "serve the metrics of the exporter itself on /exporter-metrics instead of /metrics")
+	flag.BoolVar(&runArgs.BadGateway, "metrics.bad-gateway-on-error", envBoolOrDef("METRICS_BAD_GATEWAY_ON_ERROR", false), "respond with 502 Bad Gateway if querying syslog-ng fails, instead of reporting it in syslogng_up and syslogng_scrape_error")
+	flag.BoolVar(&runArgs.Lenient, "stats.lenient", envBoolOrDef("STATS_LENIENT", false), "skip the malformed lines of the syslog-ng response instead of dropping the metrics that follow them, see syslogng_exporter_skipped_lines_total")
+	flag.BoolVar(&runArgs.WithLegacy, "stats.with-legacy", envBoolOrDef("STATS_WITH_LEGACY", false), "include legacy counters in the exported metrics (STATS PROMETHEUS WITH_LEGACY)")
 
 	flag.Parse()
+	if runArgs.JSON {
+		// the standard output is reserved for the metrics
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		slog.SetDefault(logger)
+	}
+	logger.Info("starting axosyslog-metrics-exporter", "version", Version, "license", license)
+
 	if runArgs.ServiceAddress == "" {
 		runArgs.ServiceAddress = fmt.Sprintf(":%v", runArgs.ServicePort)
 	}
 
-	logger.Info("listening", "bindAddress", runArgs.ServiceAddress, "requestTimeout", runArgs.RequestTimeout)
-	_, err := os.Stat(runArgs.SocketAddr)
-	logger.Info("testing syslog-ng control socket path", "socketPath", runArgs.SocketAddr, "found", err == nil, "error", err)
+	logger.Info("listening", "bindAddress", runArgs.ServiceAddress, "requestTimeout", runArgs.RequestTimeout, "withLegacy", runArgs.WithLegacy)
 	requestTimeout, err := time.ParseDuration(runArgs.RequestTimeout)
 	if err != nil {
 		logger.Warn("invalid request timeout, using default", "value", runArgs.RequestTimeout, "default", DEFAULT_TIMEOUT_SYSLOG, "error", err)
 		requestTimeout = DEFAULT_TIMEOUT_SYSLOG
 	}
 
-	ctl := syslogngctl.NewController(syslogngctl.NewUnixDomainSocketControlChannel(runArgs.SocketAddr))
+	scraperTTL, err := time.ParseDuration(runArgs.ScraperTTL)
+	if err != nil {
+		logger.Warn("invalid scraper TTL, using default", "value", runArgs.ScraperTTL, "default", syslogngctl.DefaultScraperTTL, "error", err)
+		scraperTTL = syslogngctl.DefaultScraperTTL
+	}
+	scraperID, err := exporter.ParseScraperIdentity(runArgs.ScraperID)
+	if err != nil {
+		logger.Error("invalid scraper identity", "error", err)
+		os.Exit(1)
+	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
-		logger := logger.With("remote", r.RemoteAddr, "userAgent", r.UserAgent(), "path", "/metrics")
+	cfg, err := loadConfig(runArgs.ConfigFile)
+	if err != nil {
+		logger.Error("loading configuration file failed", "configFile", runArgs.ConfigFile, "error", err)
+		os.Exit(1)
+	}
+	catalog, err := cfg.MetricCatalog()
+	if err != nil {
+		logger.Error("invalid metrics catalog", "configFile", runArgs.ConfigFile, "error", err)
+		os.Exit(1)
+	}
 
-		subCtx, cancel := context.WithTimeout(r.Context(), requestTimeout)
-		defer cancel()
-		mfs, err := ctl.StatsPrometheus(subCtx)
+	redactions, err := cfg.LabelRedactions()
+	if err != nil {
+		logger.Error("invalid redaction rules", "configFile", runArgs.ConfigFile, "error", err)
+		os.Exit(1)
+	}
+
+	modules, err := cfg.ExporterModules()
+	if err != nil {
+		logger.Error("invalid modules", "configFile", runArgs.ConfigFile, "error", err)
+		os.Exit(1)
+	}
+
+	derivedConfigs := cfg.DerivedMetrics.Rules
+	if runArgs.DerivedBuiltin || cfg.DerivedMetrics.Builtin {
+		derivedConfigs = append(slices.Clone(derived.BuiltinRules), derivedConfigs...)
+	}
+	derivedRules, err := derived.Compile(derivedConfigs)
+	if err != nil {
+		logger.Error("invalid derived metrics", "configFile", runArgs.ConfigFile, "error", err)
+		os.Exit(1)
+	}
+
+	relabelRules, err := relabel.Compile(cfg.MetricRelabelConfigs)
+	if err != nil {
+		logger.Error("invalid metric relabel configs", "configFile", runArgs.ConfigFile, "error", err)
+		os.Exit(1)
+	}
+
+	limiter, err := cardinality.New(cfg.Cardinality)
+	if err != nil {
+		logger.Error("invalid cardinality limits", "configFile", runArgs.ConfigFile, "error", err)
+		os.Exit(1)
+	}
+
+	externalLabels, err := cfg.ExternalLabels.Resolve()
+	if err != nil {
+		logger.Error("invalid external labels", "configFile", runArgs.ConfigFile, "error", err)
+		os.Exit(1)
+	}
+	for _, label := range runArgs.ExternalLabels {
+		name, value, err := exporter.ParseExternalLabel(label)
 		if err != nil {
-			http.Error(w, "failed to query syslog-ng stats", http.StatusBadGateway)
-			logger.Error("socket command failed", "error", err)
-			return
+			logger.Error("invalid external label", "error", err)
+			os.Exit(1)
+		}
+		externalLabels[name] = value
+	}
+	labelCollision := runArgs.LabelCollision
+	if labelCollision == "" {
+		labelCollision = cfg.ExternalLabels.OnCollision
+	}
+	labelCollisionPolicy, err := exporter.ParseLabelCollisionPolicy(labelCollision)
+	if err != nil {
+		logger.Error("invalid label collision policy", "error", err)
+		os.Exit(1)
+	}
+
+	self := exporter.NewInstrumentation()
+	self.Registry().MustRegister(limiter)
+	instanceConfigs := cfg.Instances.Sockets
+	if len(runArgs.Instances) > 0 {
+		instanceConfigs = nil
+		for _, instance := range runArgs.Instances {
+			name, socketPath, ok := strings.Cut(instance, "=")
+			if !ok {
+				logger.Error("invalid syslog-ng instance, use name=socket-path", "value", instance)
+				os.Exit(1)
+			}
+			instanceConfigs = append(instanceConfigs, InstanceConfig{Name: name, SocketPath: socketPath})
+		}
+	}
+	if len(instanceConfigs) == 0 {
+		instanceConfigs = []InstanceConfig{{SocketPath: runArgs.SocketAddr}}
+	}
+	var instances []exporter.Instance
+	for _, inst := range instanceConfigs {
+		if inst.SocketPath == "" {
+			logger.Error("syslog-ng instance without socket path", "instance", inst.Name)
+			os.Exit(1)
 		}
+		_, err := os.Stat(inst.SocketPath)
+		logger.Info("testing syslog-ng control socket path", "instance", inst.Name, "socketPath", inst.SocketPath, "found", err == nil, "error", err)
+		instances = append(instances, exporter.Instance{
+			Name:    inst.Name,
+			Timeout: inst.Timeout,
+			Controller: syslogngctl.NewController(
+				self.InstrumentControlChannel(syslogngctl.NewUnixDomainSocketControlChannel(inst.SocketPath)),
+				syslogngctl.WithLegacyStats(runArgs.WithLegacy),
+				syslogngctl.WithLenientParsing(runArgs.Lenient),
+				syslogngctl.WithMetricCatalog(catalog),
+				syslogngctl.WithScraperTTL(scraperTTL),
+				syslogngctl.WithLabelRedaction(redactions...),
+				syslogngctl.WithLogger(logger),
+				syslogngctl.WithStatsTrace(self.ObserveStats),
+			),
+		})
+	}
 
-		var resp bytes.Buffer
+	instanceLabel := runArgs.InstanceLabel
+	if instanceLabel == "" {
+		instanceLabel = cfg.Instances.Label
+	}
+	if instanceLabel == "" {
+		instanceLabel = exporter.DefaultInstanceLabel
+	}
 
-		for _, mf := range mfs {
-			_, err := expfmt.MetricFamilyToText(&resp, mf)
-			if err != nil {
-				http.Error(w, "failed to convert metrics", http.StatusInternalServerError)
-				logger.Error("metrics conversion failed", "error", err)
-				return
+	transformers := []exporter.Transformer{derivedRules}
+	if runArgs.StateFile != "" {
+		cfg.Continuity.StateFile = runArgs.StateFile
+	}
+	var tracker *continuity.Tracker
+	if cfg.Continuity.StateFile != "" {
+		tracker, err = continuity.New(cfg.Continuity,
+			continuity.WithLogger(logger),
+			instancesConfigIDs(instanceLabel, instances, requestTimeout),
+		)
+		if err != nil {
+			logger.Error("loading continuity state failed", "stateFile", cfg.Continuity.StateFile, "error", err)
+			os.Exit(1)
+		}
+		transformers = append(transformers, tracker)
+	}
+
+	exporterOpts := []exporter.Option{
+		exporter.WithLogger(logger),
+		exporter.WithTimeout(requestTimeout),
+		exporter.WithBadGatewayOnError(runArgs.BadGateway),
+		exporter.WithScraperIdentity(scraperID),
+		exporter.WithScraperTTL(scraperTTL),
+		exporter.WithInstrumentation(self),
+		exporter.WithTransformers(append(transformers, relabelRules, limiter)...),
+		exporter.WithExternalLabels(externalLabels, labelCollisionPolicy),
+		exporter.WithModules(modules),
+	}
+	if !runArgs.SeparateSelf {
+		exporterOpts = append(exporterOpts, exporter.WithGatherer(self.Registry()))
+	}
+
+	snapshotInterval, err := time.ParseDuration(runArgs.SnapshotInterval)
+	if err != nil {
+		logger.Error("invalid snapshot interval", "value", runArgs.SnapshotInterval, "error", err)
+		os.Exit(1)
+	}
+	if snapshotInterval > 0 {
+		maxStaleness, err := time.ParseDuration(runArgs.SnapshotMaxStaleness)
+		if err != nil {
+			logger.Error("invalid snapshot max staleness", "value", runArgs.SnapshotMaxStaleness, "error", err)
+			os.Exit(1)
+		}
+		exporterOpts = append(exporterOpts, exporter.WithSnapshotPolling(snapshotInterval, maxStaleness))
+	}
+
+	delayPollInterval, err := time.ParseDuration(runArgs.DelayPollInterval)
+	if err != nil {
+		logger.Error("invalid event delay poll interval", "value", runArgs.DelayPollInterval, "error", err)
+		os.Exit(1)
+	}
+	if delayPollInterval > 0 {
+		delayBuckets, err := exporter.ParseBuckets(runArgs.DelayBuckets)
+		if err != nil {
+			logger.Error("invalid event delay buckets", "value", runArgs.DelayBuckets, "error", err)
+			os.Exit(1)
+		}
+		delaySummaryWindow, err := time.ParseDuration(runArgs.DelaySummaryWindow)
+		if err != nil {
+			logger.Warn("invalid event delay summary window, using default", "value", runArgs.DelaySummaryWindow, "default", DEFAULT_DELAY_SUMMARY_WINDOW, "error", err)
+			delaySummaryWindow = DEFAULT_DELAY_SUMMARY_WINDOW
+		}
+		delaySeriesTTL, err := time.ParseDuration(runArgs.DelaySeriesTTL)
+		if err != nil {
+			logger.Warn("invalid event delay series TTL, using default", "value", runArgs.DelaySeriesTTL, "default", exporter.DefaultDelaySeriesTTL, "error", err)
+			delaySeriesTTL = exporter.DefaultDelaySeriesTTL
+		}
+		exporterOpts = append(exporterOpts, exporter.WithEventDelayAggregation(delayPollInterval, delayBuckets, delaySummaryWindow, delaySeriesTTL))
+	}
+
+	var exp *exporter.Exporter
+	if len(instances) == 1 && instances[0].Name == "" {
+		exp = exporter.New(instances[0].Controller, exporterOpts...)
+	} else {
+		if exp, err = exporter.NewMultiInstance(instanceLabel, instances, exporterOpts...); err != nil {
+			logger.Error("invalid syslog-ng instances", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if runArgs.JSON {
+		query, err := url.ParseQuery(runArgs.JSONFilter)
+		if err != nil {
+			logger.Error("invalid JSON filter", "value", runArgs.JSONFilter, "error", err)
+			os.Exit(1)
+		}
+		filter, err := exporter.ParseMetricsFilter(query)
+		if err != nil {
+			logger.Error("invalid JSON filter", "value", runArgs.JSONFilter, "error", err)
+			os.Exit(1)
+		}
+		mfs, queryErr := exp.Gather(context.Background(), JSON_SCRAPER)
+		if err := exporter.WriteJSON(os.Stdout, mfs, queryErr, filter, time.Now()); err != nil {
+			logger.Error("writing JSON failed", "error", err)
+			os.Exit(1)
+		}
+		if queryErr != nil {
+			logger.Error("querying syslog-ng failed", "error", queryErr)
+			os.Exit(2)
+		}
+		return
+	}
+
+	if runArgs.Once {
+		if runArgs.TextfileOutput == "" {
+			logger.Error("-once requires -textfile.output")
+			os.Exit(1)
+		}
+		mfs, queryErr := exp.Gather(context.Background(), TEXTFILE_SCRAPER)
+		if err := exporter.WriteTextFile(runArgs.TextfileOutput, mfs); err != nil {
+			logger.Error("writing textfile failed", "path", runArgs.TextfileOutput, "error", err)
+			os.Exit(1)
+		}
+		if tracker != nil {
+			if err := tracker.Save(); err != nil {
+				logger.Error("saving continuity state failed", "stateFile", cfg.Continuity.StateFile, "error", err)
 			}
 		}
+		if queryErr != nil {
+			logger.Error("querying syslog-ng failed", "error", queryErr)
+			os.Exit(2)
+		}
+		logger.Info("metrics written", "path", runArgs.TextfileOutput)
+		return
+	}
+	textfileInterval, err := time.ParseDuration(runArgs.TextfileInterval)
+	if err != nil || textfileInterval <= 0 {
+		logger.Error("invalid textfile interval", "value", runArgs.TextfileInterval, "error", err)
+		os.Exit(1)
+	}
 
-		bodyLen, err := io.Copy(w, &resp)
+	if runArgs.RemoteWriteURL != "" {
+		cfg.RemoteWrite.URL = runArgs.RemoteWriteURL
+	}
+	if runArgs.RemoteWriteInterval != "" {
+		if cfg.RemoteWrite.Interval, err = time.ParseDuration(runArgs.RemoteWriteInterval); err != nil {
+			logger.Error("invalid remote write interval", "value", runArgs.RemoteWriteInterval, "error", err)
+			os.Exit(1)
+		}
+	}
+	var pusher *remotewrite.Pusher
+	if cfg.RemoteWrite.URL != "" {
+		pusher, err = remotewrite.New(cfg.RemoteWrite, func(ctx context.Context) ([]*io_prometheus_client.MetricFamily, error) {
+			return exp.Gather(ctx, REMOTE_WRITE_SCRAPER)
+		}, remotewrite.WithLogger(logger))
 		if err != nil {
-			logger.Error("writing response failed", "error", err)
-			return
+			logger.Error("invalid remote write configuration", "error", err)
+			os.Exit(1)
 		}
-		logger.Info("writing response", "bodyLength", bodyLen)
-	})
+		self.Registry().MustRegister(pusher)
+	}
+
+	if runArgs.OTLPEndpoint != "" {
+		cfg.OTLP.Endpoint = runArgs.OTLPEndpoint
+	}
+	if runArgs.OTLPProtocol != "" {
+		cfg.OTLP.Protocol = runArgs.OTLPProtocol
+	}
+	if runArgs.OTLPInterval != "" {
+		if cfg.OTLP.Interval, err = time.ParseDuration(runArgs.OTLPInterval); err != nil {
+			logger.Error("invalid OTLP interval", "value", runArgs.OTLPInterval, "error", err)
+			os.Exit(1)
+		}
+	}
+	var otlpPusher *otlp.Pusher
+	if cfg.OTLP.Endpoint != "" {
+		otlpPusher, err = otlp.New(cfg.OTLP, func(ctx context.Context) ([]*io_prometheus_client.MetricFamily, error) {
+			return exp.Gather(ctx, OTLP_SCRAPER)
+		}, otlp.WithLogger(logger), otlp.WithVersion(Version))
+		if err != nil {
+			logger.Error("invalid OTLP configuration", "error", err)
+			os.Exit(1)
+		}
+		self.Registry().MustRegister(otlpPusher)
+	}
+
+	if runArgs.GraphiteAddress != "" {
+		cfg.Graphite.Address = runArgs.GraphiteAddress
+	}
+	var graphitePusher *lineoutput.Pusher
+	if cfg.Graphite.Address != "" {
+		graphitePusher, err = lineoutput.NewGraphite(cfg.Graphite, func(ctx context.Context) ([]*io_prometheus_client.MetricFamily, error) {
+			return exp.Gather(ctx, GRAPHITE_SCRAPER)
+		}, lineoutput.WithLogger(logger))
+		if err != nil {
+			logger.Error("invalid Graphite configuration", "error", err)
+			os.Exit(1)
+		}
+		self.Registry().MustRegister(graphitePusher)
+	}
+
+	if runArgs.StatsDAddress != "" {
+		cfg.StatsD.Address = runArgs.StatsDAddress
+	}
+	var statsdPusher *lineoutput.Pusher
+	if cfg.StatsD.Address != "" {
+		statsdPusher, err = lineoutput.NewStatsD(cfg.StatsD, func(ctx context.Context) ([]*io_prometheus_client.MetricFamily, error) {
+			return exp.Gather(ctx, STATSD_SCRAPER)
+		}, lineoutput.WithLogger(logger))
+		if err != nil {
+			logger.Error("invalid StatsD configuration", "error", err)
+			os.Exit(1)
+		}
+		self.Registry().MustRegister(statsdPusher)
+	}
 
-	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+	if runArgs.PushgatewayURL != "" {
+		cfg.Pushgateway.URL = runArgs.PushgatewayURL
+	}
+	if runArgs.PushgatewayJob != "" {
+		cfg.Pushgateway.Job = runArgs.PushgatewayJob
+	}
+	for _, label := range runArgs.PushgatewayGrouping {
+		name, value, found := strings.Cut(label, "=")
+		if !found {
+			logger.Error("invalid Pushgateway grouping label, must be in name=value format", "value", label)
+			os.Exit(1)
+		}
+		if cfg.Pushgateway.Grouping == nil {
+			cfg.Pushgateway.Grouping = make(map[string]string)
+		}
+		cfg.Pushgateway.Grouping[name] = value
+	}
+	cfg.Pushgateway.DeleteOnShutdown = cfg.Pushgateway.DeleteOnShutdown || runArgs.PushgatewayDelete
+	var pushgatewayPusher *pushgateway.Pusher
+	if cfg.Pushgateway.URL != "" {
+		pushgatewayPusher, err = pushgateway.New(cfg.Pushgateway, func(ctx context.Context) ([]*io_prometheus_client.MetricFamily, error) {
+			return exp.Gather(ctx, PUSHGATEWAY_SCRAPER)
+		}, pushgateway.WithLogger(logger))
+		if err != nil {
+			logger.Error("invalid Pushgateway configuration", "error", err)
+			os.Exit(1)
+		}
+		self.Registry().MustRegister(pushgatewayPusher)
+	}
+
+	var prober *exporter.Prober
+	if len(cfg.Probe.AllowedTargets) > 0 {
+		tlsConfig, err := cfg.Probe.TLSConfig.ClientConfig()
+		if err != nil {
+			logger.Error("invalid probe TLS configuration", "configFile", runArgs.ConfigFile, "error", err)
+			os.Exit(1)
+		}
+		probeTimeout := cfg.Probe.Timeout
+		if probeTimeout <= 0 {
+			probeTimeout = requestTimeout
+		}
+		// every probe uses a new controller, so only the stateless relabeling applies
+		prober, err = exporter.NewProber(cfg.Probe.AllowedTargets,
+			exporter.WithProbeLogger(logger),
+			exporter.WithProbeTimeout(probeTimeout),
+			exporter.WithProbeTLSConfig(tlsConfig),
+			exporter.WithProbeControllerOptions(
+				syslogngctl.WithLegacyStats(runArgs.WithLegacy),
+				syslogngctl.WithLenientParsing(runArgs.Lenient),
+				syslogngctl.WithMetricCatalog(catalog),
+				syslogngctl.WithLabelRedaction(redactions...),
+				syslogngctl.WithLogger(logger),
+			),
+			exporter.WithProbeTransformers(relabelRules),
+		)
+		if err != nil {
+			logger.Error("invalid probe configuration", "configFile", runArgs.ConfigFile, "error", err)
+			os.Exit(1)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", self.InstrumentHandler("/metrics", exp))
+
+	if prober != nil {
+		mux.Handle("/probe", self.InstrumentHandler("/probe", prober))
+	}
+
+	if runArgs.SeparateSelf {
+		mux.Handle("/exporter-metrics", self.InstrumentHandler("/exporter-metrics", self.Handler()))
+	}
+
+	mux.Handle("/api/v1/metrics", self.InstrumentHandler("/api/v1/metrics", exp.APIHandler()))
+
+	mux.Handle("/debug/cardinality", self.InstrumentHandler("/debug/cardinality", limiter.Handler()))
+
+	mux.Handle("/ping", self.InstrumentHandler("/ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		logger := logger.With("remote", r.RemoteAddr, "userAgent", r.UserAgent(), "path", "/ping")
 
 		subCtx, cancel := context.WithTimeout(r.Context(), requestTimeout)
 		defer cancel()
-		if err := ctl.Ping(subCtx); err != nil {
-			http.Error(w, "syslog-ng is unreachable", http.StatusBadGateway)
-			logger.Error("socket command failed", "error", err)
-			return
+		for _, inst := range instances {
+			if err := inst.Controller.Ping(subCtx); err != nil {
+				msg := "syslog-ng is unreachable"
+				if inst.Name != "" {
+					msg = fmt.Sprintf("syslog-ng instance %q is unreachable", inst.Name)
+				}
+				http.Error(w, msg, http.StatusBadGateway)
+				logger.Error("socket command failed", "instance", inst.Name, "error", err)
+				return
+			}
 		}
 		if _, err = w.Write([]byte(`PONG`)); err != nil {
 			logger.Error("writing response failed", "error", err)
 			return
 		}
 		logger.Info("pong")
-	})
+	})))
 
 	server := &http.Server{
 		Addr:    runArgs.ServiceAddress,
@@ -144,26 +647,93 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	serverErr := make(chan error, 1)
-	go func() {
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			serverErr <- err
+	go exp.Run(ctx)
+	if pusher != nil {
+		go pusher.Run(ctx)
+	}
+	if otlpPusher != nil {
+		go otlpPusher.Run(ctx)
+	}
+	if graphitePusher != nil {
+		go graphitePusher.Run(ctx)
+	}
+	if statsdPusher != nil {
+		go statsdPusher.Run(ctx)
+	}
+	// the final push to the Pushgateway is waited for on shutdown
+	pushgatewayDone := make(chan struct{})
+	if pushgatewayPusher != nil {
+		go func() {
+			pushgatewayPusher.Run(ctx)
+			close(pushgatewayDone)
+		}()
+	} else {
+		close(pushgatewayDone)
+	}
+
+	if runArgs.TextfileOutput != "" {
+		runTextfile(ctx, exp, runArgs.TextfileOutput, textfileInterval, logger)
+	} else {
+		serverErr := make(chan error, 1)
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- err
+			}
+		}()
+
+		select {
+		case err := <-serverErr:
+			logger.Error("server failed", "error", err)
+			os.Exit(1)
+		case <-ctx.Done():
+			logger.Info("shutdown signal received, stopping server")
 		}
-	}()
 
-	select {
-	case err := <-serverErr:
-		logger.Error("server failed", "error", err)
-		os.Exit(1)
-	case <-ctx.Done():
-		logger.Info("shutdown signal received, stopping server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error("graceful shutdown failed", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("server stopped")
 	}
+	<-pushgatewayDone
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), requestTimeout)
-	defer cancel()
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		logger.Error("graceful shutdown failed", "error", err)
-		os.Exit(1)
+	if tracker != nil {
+		if err := tracker.Save(); err != nil {
+			logger.Error("saving continuity state failed", "stateFile", cfg.Continuity.StateFile, "error", err)
+		}
+	}
+}
+
+// runTextfile writes the metrics to the textfile at the interval until ctx is done
+func runTextfile(ctx context.Context, exp *exporter.Exporter, path string, interval time.Duration, logger *slog.Logger) {
+	logger.Info("writing metrics to textfile", "path", path, "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		mfs, _ := exp.Gather(ctx, TEXTFILE_SCRAPER)
+		if err := exporter.WriteTextFile(path, mfs); err != nil {
+			logger.Error("writing textfile failed", "path", path, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			logger.Info("shutdown signal received, stopped writing textfile")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// instancesConfigIDs returns the option of the tracker querying the config IDs of the instances, so that reloading an
+// instance resets only the counters of that instance
+func instancesConfigIDs(instanceLabel string, instances []exporter.Instance, timeout time.Duration) continuity.Option {
+	if len(instances) == 1 && instances[0].Name == "" {
+		return continuity.WithConfigID(instances[0].Controller.ConfigID, timeout)
+	}
+	configIDs := make(map[string]func(ctx context.Context) (string, error), len(instances))
+	for _, inst := range instances {
+		configIDs[inst.Name] = inst.Controller.ConfigID
 	}
-	logger.Info("server stopped")
+	return continuity.WithInstanceConfigIDs(instanceLabel, configIDs, timeout)
 }
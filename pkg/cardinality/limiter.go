@@ -0,0 +1,273 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cardinality limits the number of series of metric families.
+package cardinality
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+)
+
+// OverflowValue is the value of every label of the series aggregating the series beyond the limit
+const OverflowValue = "__overflow__"
+
+// OverflowAction tells what happens to the series beyond the limit
+type OverflowAction string
+
+const (
+	// Aggregate sums the series beyond the limit into a single series, whose labels are all set to OverflowValue.
+	// Only counters, gauges and untyped metrics can be aggregated, other series are dropped. The aggregated counter
+	// stays monotonic when its series reset or leave the aggregate.
+	Aggregate OverflowAction = "aggregate"
+	// Drop drops the series beyond the limit
+	Drop OverflowAction = "drop"
+)
+
+// Config is the configuration of the limiter, a limit of 0 means unlimited
+type Config struct {
+	// MaxSeries limits the number of series of all metric families together
+	MaxSeries int `yaml:"max_series"`
+	// MaxSeriesPerFamily limits the number of series of each metric family
+	MaxSeriesPerFamily int `yaml:"max_series_per_family"`
+	// Families overrides MaxSeriesPerFamily for the named metric families
+	Families map[string]int `yaml:"families"`
+	// Overflow is the action for the series beyond the limits (default: aggregate)
+	Overflow OverflowAction `yaml:"overflow"`
+}
+
+// Limiter limits the number of series of the metric families.
+//
+// Series that were admitted in the previous scrape are preferred, so that the set of exported series does not change
+// between scrapes as long as the series of syslog-ng do not change.
+type Limiter struct {
+	cfg Config
+
+	mu sync.Mutex
+	// admitted are the label sets of the series admitted in the last scrape by family
+	admitted map[string]map[string]struct{}
+	// overflowed are the last values of the series beyond the limit in the last scrape by family and label set
+	overflowed map[string]map[string]float64
+	// offsets are the sums of the values of the aggregated counters before they reset or left the aggregate
+	offsets map[string]float64
+	dropped map[string]float64
+	stats   []FamilyStats
+
+	droppedDesc *prometheus.Desc
+}
+
+func New(cfg Config) (*Limiter, error) {
+	switch cfg.Overflow {
+	case "":
+		cfg.Overflow = Aggregate
+	case Aggregate, Drop:
+	default:
+		return nil, fmt.Errorf("invalid overflow action %q, use aggregate or drop", cfg.Overflow)
+	}
+	if cfg.MaxSeries < 0 || cfg.MaxSeriesPerFamily < 0 {
+		return nil, fmt.Errorf("series limits must not be negative")
+	}
+	for name, limit := range cfg.Families {
+		if limit < 0 {
+			return nil, fmt.Errorf("series limit of %q must not be negative", name)
+		}
+	}
+
+	return &Limiter{
+		cfg:        cfg,
+		admitted:   make(map[string]map[string]struct{}),
+		overflowed: make(map[string]map[string]float64),
+		offsets:    make(map[string]float64),
+		dropped:    make(map[string]float64),
+		droppedDesc: prometheus.NewDesc(
+			"syslogng_exporter_series_dropped_total",
+			"Number of times series started to be aggregated or dropped because the cardinality limit of their metric family or the global limit was reached.",
+			[]string{"family"}, nil,
+		),
+	}, nil
+}
+
+func (l *Limiter) familyLimit(name string) int {
+	if limit, ok := l.cfg.Families[name]; ok {
+		return limit
+	}
+	return l.cfg.MaxSeriesPerFamily
+}
+
+// Transform limits the series of the metric families, and records their cardinality for Stats
+func (l *Limiter) Transform(mfs []*io_prometheus_client.MetricFamily) []*io_prometheus_client.MetricFamily {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stats = familyStats(mfs)
+
+	slices.SortFunc(mfs, func(a, b *io_prometheus_client.MetricFamily) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+
+	keys := make([][]string, len(mfs))
+	admit := make([][]bool, len(mfs))
+	counts := make([]int, len(mfs))
+	total := 0
+
+	// series admitted in the last scrape first, then the new ones
+	for pass := range 2 {
+		for i, mf := range mfs {
+			if pass == 0 {
+				keys[i] = make([]string, len(mf.Metric))
+				admit[i] = make([]bool, len(mf.Metric))
+				for j, m := range mf.Metric {
+					keys[i][j] = labelSetKey(m.Label)
+				}
+			}
+			limit := l.familyLimit(mf.GetName())
+			previous := l.admitted[mf.GetName()]
+			for j := range mf.Metric {
+				if admit[i][j] {
+					continue
+				}
+				if _, ok := previous[keys[i][j]]; pass == 0 && !ok {
+					continue
+				}
+				if (limit > 0 && counts[i] >= limit) || (l.cfg.MaxSeries > 0 && total >= l.cfg.MaxSeries) {
+					continue
+				}
+				admit[i][j] = true
+				counts[i]++
+				total++
+			}
+		}
+	}
+
+	admitted := make(map[string]map[string]struct{}, len(mfs))
+	overflowed := make(map[string]map[string]float64, len(mfs))
+	offsets := make(map[string]float64, len(mfs))
+	for i, mf := range mfs {
+		name := mf.GetName()
+		var kept, overflow []*io_prometheus_client.Metric
+		familyAdmitted := make(map[string]struct{}, counts[i])
+		previous := l.overflowed[name]
+		familyOverflowed := make(map[string]float64)
+		offset := l.offsets[name]
+		for j, m := range mf.Metric {
+			if admit[i][j] {
+				kept = append(kept, m)
+				familyAdmitted[keys[i][j]] = struct{}{}
+				continue
+			}
+			overflow = append(overflow, m)
+			last, known := previous[keys[i][j]]
+			if !known {
+				// counted only when the series starts to overflow, not at every scrape
+				l.dropped[name]++
+			}
+			value := m.GetCounter().GetValue()
+			if known && value < last {
+				offset += last
+			}
+			familyOverflowed[keys[i][j]] = value
+		}
+		for key, last := range previous {
+			if _, ok := familyOverflowed[key]; !ok {
+				offset += last
+			}
+		}
+		admitted[name] = familyAdmitted
+		if len(familyOverflowed) > 0 {
+			overflowed[name] = familyOverflowed
+		}
+		if offset > 0 {
+			offsets[name] = offset
+		}
+
+		if len(overflow) > 0 && l.cfg.Overflow == Aggregate {
+			if m := aggregate(overflow, offset); m != nil {
+				kept = append(kept, m)
+			}
+		}
+		mf.Metric = kept
+	}
+	l.admitted = admitted
+	l.overflowed = overflowed
+	l.offsets = offsets
+
+	return slices.DeleteFunc(mfs, func(mf *io_prometheus_client.MetricFamily) bool {
+		return len(mf.Metric) == 0
+	})
+}
+
+// aggregate sums the series into a series whose labels are all set to OverflowValue, adding the offset to counters,
+// it returns nil if the series cannot be summed
+func aggregate(metrics []*io_prometheus_client.Metric, offset float64) *io_prometheus_client.Metric {
+	labelNames := make(map[string]struct{})
+	var sum float64
+	for _, m := range metrics {
+		for _, lp := range m.Label {
+			labelNames[lp.GetName()] = struct{}{}
+		}
+		switch {
+		case m.Counter != nil:
+			sum += m.Counter.GetValue()
+		case m.Gauge != nil:
+			sum += m.Gauge.GetValue()
+		case m.Untyped != nil:
+			sum += m.Untyped.GetValue()
+		default:
+			return nil
+		}
+	}
+
+	res := &io_prometheus_client.Metric{}
+	for _, name := range slices.Sorted(maps.Keys(labelNames)) {
+		res.Label = append(res.Label, &io_prometheus_client.LabelPair{Name: new(name), Value: new(OverflowValue)})
+	}
+	switch first := metrics[0]; {
+	case first.Counter != nil:
+		res.Counter = &io_prometheus_client.Counter{Value: new(offset + sum)}
+	case first.Gauge != nil:
+		res.Gauge = &io_prometheus_client.Gauge{Value: new(sum)}
+	default:
+		res.Untyped = &io_prometheus_client.Untyped{Value: new(sum)}
+	}
+	return res
+}
+
+func labelSetKey(labels []*io_prometheus_client.LabelPair) string {
+	pairs := make([]string, 0, len(labels))
+	for _, lp := range labels {
+		pairs = append(pairs, lp.GetName()+"\x00"+lp.GetValue())
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "\x01")
+}
+
+// Describe implements prometheus.Collector for the syslogng_exporter_series_dropped_total counter
+func (l *Limiter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- l.droppedDesc
+}
+
+// Collect implements prometheus.Collector for the syslogng_exporter_series_dropped_total counter
+func (l *Limiter) Collect(ch chan<- prometheus.Metric) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for family, dropped := range l.dropped {
+		ch <- prometheus.MustNewConstMetric(l.droppedDesc, prometheus.CounterValue, dropped, family)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cardinality
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/internal/metrictest"
+)
+
+const input = `# TYPE syslogng_input_events_total counter
+syslogng_input_events_total{host="a",id="s_net"} 1
+syslogng_input_events_total{host="b",id="s_net"} 2
+syslogng_input_events_total{host="c",id="s_net"} 3
+syslogng_input_events_total{host="d",id="s_net"} 4
+# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{id="d_file"} 10
+`
+
+func TestLimiterPerFamily(t *testing.T) {
+	l, err := New(Config{MaxSeriesPerFamily: 2})
+	require.NoError(t, err)
+
+	assert.Equal(t, `# TYPE syslogng_input_events_total counter
+syslogng_input_events_total{host="a",id="s_net"} 1
+syslogng_input_events_total{host="b",id="s_net"} 2
+syslogng_input_events_total{host="__overflow__",id="__overflow__"} 7
+# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{id="d_file"} 10
+`, metrictest.Text(l.Transform(metrictest.Parse(t, input))))
+
+	// previously admitted series are kept even if new series appear before them,
+	// the aggregate keeps the values of the series that left it
+	next := `# TYPE syslogng_input_events_total counter
+syslogng_input_events_total{host="0",id="s_net"} 5
+syslogng_input_events_total{host="b",id="s_net"} 2
+syslogng_input_events_total{host="a",id="s_net"} 1
+# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{id="d_file"} 10
+`
+	assert.Equal(t, `# TYPE syslogng_input_events_total counter
+syslogng_input_events_total{host="b",id="s_net"} 2
+syslogng_input_events_total{host="a",id="s_net"} 1
+syslogng_input_events_total{host="__overflow__",id="__overflow__"} 12
+# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{id="d_file"} 10
+`, metrictest.Text(l.Transform(metrictest.Parse(t, next))))
+
+	// the aggregate stays monotonic when its series reset, and the series are counted once
+	assert.Contains(t, metrictest.Text(l.Transform(metrictest.Parse(t, strings.Replace(next, "} 5", "} 1", 1)))),
+		`syslogng_input_events_total{host="__overflow__",id="__overflow__"} 13`+"\n")
+
+	assert.NoError(t, testutil.CollectAndCompare(l, strings.NewReader(`
+# HELP syslogng_exporter_series_dropped_total Number of times series started to be aggregated or dropped because the cardinality limit of their metric family or the global limit was reached.
+# TYPE syslogng_exporter_series_dropped_total counter
+syslogng_exporter_series_dropped_total{family="syslogng_input_events_total"} 3
+`)))
+}
+
+func TestLimiterGlobalDrop(t *testing.T) {
+	l, err := New(Config{MaxSeries: 3, Families: map[string]int{"syslogng_output_events_total": 0}, Overflow: Drop})
+	require.NoError(t, err)
+
+	assert.Equal(t, `# TYPE syslogng_input_events_total counter
+syslogng_input_events_total{host="a",id="s_net"} 1
+syslogng_input_events_total{host="b",id="s_net"} 2
+syslogng_input_events_total{host="c",id="s_net"} 3
+`, metrictest.Text(l.Transform(metrictest.Parse(t, input))))
+}
+
+func TestLimiterHandler(t *testing.T) {
+	l, err := New(Config{})
+	require.NoError(t, err)
+	l.Transform(metrictest.Parse(t, input))
+
+	rec := httptest.NewRecorder()
+	l.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/cardinality?top=1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var res cardinalityResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, []FamilyStats{
+		{Name: "syslogng_input_events_total", Series: 4, Labels: []LabelStats{{Name: "host", Values: 4}}},
+	}, res.Families)
+
+	rec = httptest.NewRecorder()
+	l.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/cardinality?top=x", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	_, err := New(Config{Overflow: "explode"})
+	assert.Error(t, err)
+	_, err = New(Config{MaxSeries: -1})
+	assert.Error(t, err)
+}
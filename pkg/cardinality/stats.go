@@ -0,0 +1,103 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cardinality
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+)
+
+// DefaultTop is the default number of families and labels listed by Handler
+const DefaultTop = 10
+
+// FamilyStats is the cardinality of a metric family before limiting
+type FamilyStats struct {
+	Name   string       `json:"name"`
+	Series int          `json:"series"`
+	Labels []LabelStats `json:"labels"`
+}
+
+// LabelStats is the number of distinct values of a label of a metric family
+type LabelStats struct {
+	Name   string `json:"name"`
+	Values int    `json:"values"`
+}
+
+func familyStats(mfs []*io_prometheus_client.MetricFamily) []FamilyStats {
+	stats := make([]FamilyStats, 0, len(mfs))
+	for _, mf := range mfs {
+		values := make(map[string]map[string]struct{})
+		for _, m := range mf.Metric {
+			for _, lp := range m.Label {
+				if values[lp.GetName()] == nil {
+					values[lp.GetName()] = make(map[string]struct{})
+				}
+				values[lp.GetName()][lp.GetValue()] = struct{}{}
+			}
+		}
+		fs := FamilyStats{Name: mf.GetName(), Series: len(mf.Metric), Labels: make([]LabelStats, 0, len(values))}
+		for name, vs := range values {
+			fs.Labels = append(fs.Labels, LabelStats{Name: name, Values: len(vs)})
+		}
+		slices.SortFunc(fs.Labels, func(a, b LabelStats) int {
+			return cmp.Or(cmp.Compare(b.Values, a.Values), cmp.Compare(a.Name, b.Name))
+		})
+		stats = append(stats, fs)
+	}
+	slices.SortFunc(stats, func(a, b FamilyStats) int {
+		return cmp.Or(cmp.Compare(b.Series, a.Series), cmp.Compare(a.Name, b.Name))
+	})
+	return stats
+}
+
+// Stats returns the top metric families by series count in the last scrape before limiting,
+// along with their labels by the number of distinct values
+func (l *Limiter) Stats(top int) []FamilyStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := slices.Clone(l.stats[:min(top, len(l.stats))])
+	for i := range stats {
+		stats[i].Labels = stats[i].Labels[:min(top, len(stats[i].Labels))]
+	}
+	return stats
+}
+
+type cardinalityResponse struct {
+	Families []FamilyStats `json:"families"`
+}
+
+// Handler lists the top metric families and labels by series count of the last scrape as JSON.
+// The number of listed items can be set by the top query parameter (default: DefaultTop).
+func (l *Limiter) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		top := DefaultTop
+		if s := r.URL.Query().Get("top"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				http.Error(w, "invalid top parameter", http.StatusBadRequest)
+				return
+			}
+			top = n
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(cardinalityResponse{Families: l.Stats(top)})
+	})
+}
@@ -0,0 +1,124 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package continuity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+)
+
+const stateVersion = 1
+
+// state is the content of the state file
+type state struct {
+	Version int `json:"version"`
+	// ConfigIDs are the last seen config IDs of syslog-ng by instance, the single instance is unnamed
+	ConfigIDs map[string]string `json:"config_ids,omitempty"`
+	// Series are the tracked series by family and label set
+	Series map[string]map[string]*series `json:"series"`
+	// Resets are the number of detected counter resets by family
+	Resets map[string]float64 `json:"resets"`
+}
+
+type series struct {
+	// Last is the last seen value of the counter of syslog-ng
+	Last float64 `json:"last"`
+	// Offset is the sum of the values of the counter before its resets
+	Offset  float64   `json:"offset"`
+	Created time.Time `json:"created"`
+	Seen    time.Time `json:"seen"`
+}
+
+func newState() *state {
+	return &state{
+		Version:   stateVersion,
+		ConfigIDs: make(map[string]string),
+		Series:    make(map[string]map[string]*series),
+		Resets:    make(map[string]float64),
+	}
+}
+
+// loadState reads the state file, a missing file means an empty state
+func loadState(path string) (*state, error) {
+	dat, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return newState(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := newState()
+	if err := json.Unmarshal(dat, s); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+	if s.Version != stateVersion {
+		return nil, fmt.Errorf("unsupported version %d of state file %s", s.Version, path)
+	}
+	if s.ConfigIDs == nil {
+		s.ConfigIDs = make(map[string]string)
+	}
+	if s.Series == nil {
+		s.Series = make(map[string]map[string]*series)
+	}
+	if s.Resets == nil {
+		s.Resets = make(map[string]float64)
+	}
+	return s, nil
+}
+
+// save writes the state file atomically, so that a crash does not leave a truncated file behind
+func (s *state) save(path string) error {
+	dat, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(dat); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// seriesKey identifies a series by its labels in the text exposition format, which is readable in the state file
+func seriesKey(labels []*io_prometheus_client.LabelPair) string {
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, l.GetName()+"="+strconv.Quote(l.GetValue()))
+	}
+	slices.Sort(pairs)
+	return "{" + strings.Join(pairs, ",") + "}"
+}
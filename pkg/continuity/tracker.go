@@ -0,0 +1,311 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package continuity keeps the counters of syslog-ng continuous across the restarts and reloads of syslog-ng and
+// the exporter. It detects the resets of the counters, and exposes lifetime counters that are the sum of the values
+// of the counters before and after their resets. The state is persisted to a file.
+package continuity
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	DefaultSaveInterval = time.Minute
+	DefaultSeriesTTL    = 24 * time.Hour
+
+	resetsMetricName = "syslogng_counter_resets_total"
+)
+
+// Config is the configuration of the tracker
+type Config struct {
+	// StateFile is the path of the file persisting the state, continuity is disabled without it
+	StateFile string `yaml:"state_file"`
+	// Families are the names of the tracked counter families (default: every counter)
+	Families []string `yaml:"families"`
+	// ResetOnConfigChange treats every counter as reset when the configuration of syslog-ng changes, like syslog-ng
+	// 3.x does on reload for some counters. Otherwise only the counters whose value decreased are treated as reset.
+	ResetOnConfigChange bool `yaml:"reset_on_config_change"`
+	// SaveInterval is the maximum time between saving the state file (default: DefaultSaveInterval).
+	// The state is saved immediately when a reset is detected.
+	SaveInterval time.Duration `yaml:"save_interval"`
+	// SeriesTTL is the time after which series that disappeared are forgotten (default: DefaultSeriesTTL)
+	SeriesTTL time.Duration `yaml:"series_ttl"`
+}
+
+// Tracker detects the resets of the counters of syslog-ng, and appends the lifetime counters and the
+// syslogng_counter_resets_total metric to the metric families
+type Tracker struct {
+	cfg    Config
+	logger *slog.Logger
+	// configIDs query the config IDs of the syslog-ng instances by the value of their instance label
+	configIDs     map[string]func(ctx context.Context) (string, error)
+	instanceLabel string
+	timeout       time.Duration
+	now           func() time.Time
+
+	mu      sync.Mutex
+	state   *state
+	savedAt time.Time
+}
+
+// Option is an option for New
+type Option func(*Tracker)
+
+// WithLogger sets the logger of the tracker (default: slog.Default)
+func WithLogger(logger *slog.Logger) Option {
+	return func(t *Tracker) {
+		t.logger = logger
+	}
+}
+
+// WithConfigID sets the function querying the identifier of the configuration of syslog-ng (CONFIG ID), e.g.
+// syslogngctl.Controller.ConfigID, to detect the reloads of syslog-ng
+func WithConfigID(configID func(ctx context.Context) (string, error), timeout time.Duration) Option {
+	return func(t *Tracker) {
+		t.configIDs = map[string]func(ctx context.Context) (string, error){"": configID}
+		t.timeout = timeout
+	}
+}
+
+// WithInstanceConfigIDs sets the functions querying the config IDs of several syslog-ng instances by the name of the
+// instance, whose series are told apart by the value of the label. Reloading an instance resets only its counters.
+func WithInstanceConfigIDs(label string, configIDs map[string]func(ctx context.Context) (string, error), timeout time.Duration) Option {
+	return func(t *Tracker) {
+		t.configIDs = configIDs
+		t.instanceLabel = label
+		t.timeout = timeout
+	}
+}
+
+// New creates a tracker and loads its state file, if it exists
+func New(cfg Config, opts ...Option) (*Tracker, error) {
+	if cfg.StateFile == "" {
+		return nil, fmt.Errorf("continuity requires a state file")
+	}
+	if cfg.SaveInterval <= 0 {
+		cfg.SaveInterval = DefaultSaveInterval
+	}
+	if cfg.SeriesTTL <= 0 {
+		cfg.SeriesTTL = DefaultSeriesTTL
+	}
+	t := &Tracker{
+		cfg:    cfg,
+		logger: slog.Default(),
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	var err error
+	if t.state, err = loadState(cfg.StateFile); err != nil {
+		return nil, err
+	}
+	t.savedAt = t.now()
+	return t, nil
+}
+
+// LifetimeName is the name of the lifetime counter of the counter family
+func LifetimeName(name string) string {
+	if base, found := strings.CutSuffix(name, "_total"); found {
+		return base + "_lifetime_total"
+	}
+	return name + "_lifetime"
+}
+
+// Transform detects the resets of the tracked counters, and appends their lifetime counters and the
+// syslogng_counter_resets_total metric. Failed queries of syslog-ng (no metric families) are ignored.
+func (t *Tracker) Transform(mfs []*io_prometheus_client.MetricFamily) []*io_prometheus_client.MetricFamily {
+	if len(mfs) == 0 {
+		return mfs
+	}
+	configIDs := t.queryConfigIDs()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	// configChanged are the instances whose configuration changed, instances failing to report it are unchanged
+	configChanged := make(map[string]bool)
+	for instance, configID := range configIDs {
+		if previous := t.state.ConfigIDs[instance]; previous != "" && configID != previous {
+			t.logger.Info("configuration of syslog-ng changed", "instance", instance, "configID", configID, "previousConfigID", previous)
+			configChanged[instance] = true
+		}
+		t.state.ConfigIDs[instance] = configID
+	}
+	dirty := len(configChanged) > 0
+
+	var lifetimes []*io_prometheus_client.MetricFamily
+	var tracked []string
+	for _, mf := range mfs {
+		if mf.GetType() != io_prometheus_client.MetricType_COUNTER || !t.tracks(mf.GetName()) {
+			continue
+		}
+		name := mf.GetName()
+		tracked = append(tracked, name)
+		known := t.state.Series[name]
+		if known == nil {
+			known = make(map[string]*series)
+			t.state.Series[name] = known
+		}
+
+		var reset bool
+		lifetime := &io_prometheus_client.MetricFamily{
+			Name: new(LifetimeName(name)),
+			Help: new("Lifetime value of " + name + " across the restarts and reloads of syslog-ng."),
+			Type: io_prometheus_client.MetricType_COUNTER.Enum(),
+		}
+		for _, m := range mf.GetMetric() {
+			if m.Counter == nil {
+				continue
+			}
+			value := m.GetCounter().GetValue()
+			key := seriesKey(m.GetLabel())
+			s := known[key]
+			switch {
+			case s == nil:
+				s = &series{Created: now}
+				known[key] = s
+			case value < s.Last || (t.cfg.ResetOnConfigChange && configChanged[t.instance(m.GetLabel())]):
+				s.Offset += s.Last
+				reset = true
+			}
+			s.Last = value
+			s.Seen = now
+
+			lifetime.Metric = append(lifetime.Metric, &io_prometheus_client.Metric{
+				Label: m.GetLabel(),
+				Counter: &io_prometheus_client.Counter{
+					Value:            new(s.Offset + value),
+					CreatedTimestamp: timestamppb.New(s.Created),
+				},
+			})
+		}
+		if reset {
+			t.state.Resets[name]++
+			dirty = true
+		}
+		lifetimes = append(lifetimes, lifetime)
+	}
+	t.forgetStaleSeries(now)
+
+	mfs = append(mfs, lifetimes...)
+	if len(tracked) > 0 {
+		mfs = append(mfs, t.resetsMetric(tracked))
+	}
+
+	if dirty || now.Sub(t.savedAt) >= t.cfg.SaveInterval {
+		t.save(now)
+	}
+	return mfs
+}
+
+// Save saves the state file, e.g. on shutdown
+func (t *Tracker) Save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.savedAt = t.now()
+	return t.state.save(t.cfg.StateFile)
+}
+
+func (t *Tracker) save(now time.Time) {
+	if err := t.state.save(t.cfg.StateFile); err != nil {
+		t.logger.Error("saving continuity state failed", "stateFile", t.cfg.StateFile, "error", err)
+		return
+	}
+	t.savedAt = now
+}
+
+func (t *Tracker) tracks(name string) bool {
+	return len(t.cfg.Families) == 0 || slices.Contains(t.cfg.Families, name)
+}
+
+// queryConfigIDs queries the config IDs of the instances concurrently, the failed ones are left out
+func (t *Tracker) queryConfigIDs() map[string]string {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	ids := make(map[string]string, len(t.configIDs))
+	for instance, configID := range t.configIDs {
+		wg.Go(func() {
+			id, err := configID(ctx)
+			if err != nil {
+				t.logger.Warn("querying the config ID of syslog-ng failed", "instance", instance, "error", err)
+				return
+			}
+			if id = strings.TrimSpace(id); id != "" {
+				mu.Lock()
+				ids[instance] = id
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	return ids
+}
+
+// instance returns the name of the instance of the series, empty for a single instance
+func (t *Tracker) instance(labels []*io_prometheus_client.LabelPair) string {
+	if t.instanceLabel == "" {
+		return ""
+	}
+	for _, l := range labels {
+		if l.GetName() == t.instanceLabel {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
+
+func (t *Tracker) forgetStaleSeries(now time.Time) {
+	for name, known := range t.state.Series {
+		for key, s := range known {
+			if now.Sub(s.Seen) > t.cfg.SeriesTTL {
+				delete(known, key)
+			}
+		}
+		if len(known) == 0 {
+			delete(t.state.Series, name)
+			delete(t.state.Resets, name)
+		}
+	}
+}
+
+func (t *Tracker) resetsMetric(families []string) *io_prometheus_client.MetricFamily {
+	mf := &io_prometheus_client.MetricFamily{
+		Name: new(resetsMetricName),
+		Help: new("Number of times the counters of the metric family were found reset, e.g. by a restart of syslog-ng."),
+		Type: io_prometheus_client.MetricType_COUNTER.Enum(),
+	}
+	slices.Sort(families)
+	for _, name := range families {
+		mf.Metric = append(mf.Metric, &io_prometheus_client.Metric{
+			Label:   []*io_prometheus_client.LabelPair{{Name: new("family"), Value: new(name)}},
+			Counter: &io_prometheus_client.Counter{Value: new(t.state.Resets[name])},
+		})
+	}
+	return mf
+}
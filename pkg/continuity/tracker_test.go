@@ -0,0 +1,134 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package continuity
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/internal/metrictest"
+)
+
+func snapshot(t *testing.T, delivered, dropped float64) []*io_prometheus_client.MetricFamily {
+	return metrictest.Parse(t, fmt.Sprintf(`# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{id="d1",result="delivered"} %v
+syslogng_output_events_total{id="d1",result="dropped"} %v
+# TYPE syslogng_memory_queue_events gauge
+syslogng_memory_queue_events{id="d1"} 3
+`, delivered, dropped))
+}
+
+// samples returns the sample lines of the metric families added by the tracker
+func samples(mfs []*io_prometheus_client.MetricFamily) string {
+	return metrictest.Samples(mfs[2:])
+}
+
+func TestTracker(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	tracker, err := New(Config{StateFile: stateFile})
+	require.NoError(t, err)
+
+	assert.Equal(t, `syslogng_counter_resets_total{family="syslogng_output_events_total"} 0
+syslogng_output_events_lifetime_total{id="d1",result="delivered"} 10
+syslogng_output_events_lifetime_total{id="d1",result="dropped"} 1
+`, samples(tracker.Transform(snapshot(t, 10, 1))))
+	assert.NoFileExists(t, stateFile, "the state is saved at the save interval")
+
+	assert.Equal(t, `syslogng_counter_resets_total{family="syslogng_output_events_total"} 0
+syslogng_output_events_lifetime_total{id="d1",result="delivered"} 15
+syslogng_output_events_lifetime_total{id="d1",result="dropped"} 1
+`, samples(tracker.Transform(snapshot(t, 15, 1))))
+
+	// syslog-ng restarted
+	assert.Equal(t, `syslogng_counter_resets_total{family="syslogng_output_events_total"} 1
+syslogng_output_events_lifetime_total{id="d1",result="delivered"} 18
+syslogng_output_events_lifetime_total{id="d1",result="dropped"} 1
+`, samples(tracker.Transform(snapshot(t, 3, 0))))
+	assert.FileExists(t, stateFile, "the state is saved on reset")
+	assert.Len(t, tracker.Transform(nil), 0)
+
+	// the exporter restarted, and syslog-ng restarted meanwhile
+	tracker, err = New(Config{StateFile: stateFile})
+	require.NoError(t, err)
+	assert.Equal(t, `syslogng_counter_resets_total{family="syslogng_output_events_total"} 2
+syslogng_output_events_lifetime_total{id="d1",result="delivered"} 20
+syslogng_output_events_lifetime_total{id="d1",result="dropped"} 2
+`, samples(tracker.Transform(snapshot(t, 2, 1))))
+}
+
+func TestTrackerConfigChange(t *testing.T) {
+	configID := "1"
+	tracker, err := New(Config{StateFile: filepath.Join(t.TempDir(), "state.json"), ResetOnConfigChange: true},
+		WithConfigID(func(_ context.Context) (string, error) { return configID + "\n", nil }, time.Second))
+	require.NoError(t, err)
+
+	tracker.Transform(snapshot(t, 10, 1))
+	assert.Contains(t, samples(tracker.Transform(snapshot(t, 12, 1))), `syslogng_output_events_lifetime_total{id="d1",result="delivered"} 12`+"\n")
+
+	// reloaded and counted past the previous value
+	configID = "2"
+	out := samples(tracker.Transform(snapshot(t, 14, 1)))
+	assert.Contains(t, out, `syslogng_output_events_lifetime_total{id="d1",result="delivered"} 26`+"\n")
+	assert.Contains(t, out, `syslogng_counter_resets_total{family="syslogng_output_events_total"} 1`+"\n")
+}
+
+func TestTrackerFamilies(t *testing.T) {
+	tracker, err := New(Config{StateFile: filepath.Join(t.TempDir(), "state.json"), Families: []string{"syslogng_input_events_total"}})
+	require.NoError(t, err)
+	assert.Len(t, tracker.Transform(snapshot(t, 10, 1)), 2)
+
+	_, err = New(Config{})
+	assert.Error(t, err)
+}
+
+func TestTrackerInstanceConfigChange(t *testing.T) {
+	configIDs := map[string]string{"a": "1", "b": "1"}
+	queries := make(map[string]func(ctx context.Context) (string, error))
+	for _, instance := range []string{"a", "b", "down"} {
+		queries[instance] = func(_ context.Context) (string, error) {
+			if instance == "down" {
+				return "", fmt.Errorf("connection refused")
+			}
+			return configIDs[instance], nil
+		}
+	}
+	tracker, err := New(Config{StateFile: filepath.Join(t.TempDir(), "state.json"), ResetOnConfigChange: true},
+		WithInstanceConfigIDs("syslogng", queries, time.Second))
+	require.NoError(t, err)
+
+	snapshot := func(value float64) []*io_prometheus_client.MetricFamily {
+		return metrictest.Parse(t, fmt.Sprintf(`# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{id="d1",syslogng="a"} %v
+syslogng_output_events_total{id="d1",syslogng="b"} %v
+# TYPE syslogng_memory_queue_events gauge
+syslogng_memory_queue_events{id="d1",syslogng="a"} 3
+`, value, value))
+	}
+
+	tracker.Transform(snapshot(10))
+	// b reloaded, while an other instance is down
+	configIDs["b"] = "2"
+	assert.Equal(t, `syslogng_counter_resets_total{family="syslogng_output_events_total"} 1
+syslogng_output_events_lifetime_total{id="d1",syslogng="a"} 12
+syslogng_output_events_lifetime_total{id="d1",syslogng="b"} 22
+`, samples(tracker.Transform(snapshot(12))))
+}
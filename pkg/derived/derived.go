@@ -0,0 +1,187 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package derived computes gauges derived from the metrics of syslog-ng, e.g. rates and ratios, so that they are
+// available without PromQL. The gauges are defined by expressions of a small subset of PromQL, see Config.
+//
+// Rates are computed from the previous snapshot of the metrics, so they describe the time since the previous query
+// of syslog-ng.
+package derived
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+)
+
+// Config is a derived metric as it appears in the configuration file.
+//
+// The expression supports
+//   - selectors of the series of the metric families, e.g. syslogng_output_events_total{result=~"dropped|queued"}
+//   - rate(selector): the per-second increase of counters since the previous snapshot, handling counter resets
+//   - deriv(selector): the per-second change of gauges since the previous snapshot
+//   - sum, min, max, avg and count, optionally with by (labels) or without (labels)
+//   - the +, -, * and / operators between numbers and vectors; vectors are matched by their whole label set
+//
+// Series whose value is not a finite number (e.g. dividing by zero) are omitted.
+type Config struct {
+	Name string `yaml:"name"`
+	Help string `yaml:"help"`
+	Expr string `yaml:"expr"`
+}
+
+// BuiltinRules are the derived metrics describing the destinations
+var BuiltinRules = []Config{
+	{
+		Name: "syslogng_output_drop_ratio",
+		Help: "Ratio of the messages dropped by the destination to the messages delivered or dropped since the previous snapshot.",
+		Expr: `sum without (result) (rate(syslogng_output_events_total{result="dropped"})) / sum without (result) (rate(syslogng_output_events_total{result=~"delivered|dropped"}))`,
+	},
+	{
+		Name: "syslogng_output_queue_growth_per_second",
+		Help: "Change of the number of messages queued by the destination per second since the previous snapshot.",
+		Expr: `sum without (result) (deriv(syslogng_output_events_total{result="queued"}))`,
+	},
+}
+
+// Rule is a compiled derived metric
+type Rule struct {
+	name string
+	help string
+	expr node
+}
+
+// Compile parses the expression of the derived metric
+func (c Config) Compile() (*Rule, error) {
+	if !model.IsValidLegacyMetricName(c.Name) {
+		return nil, fmt.Errorf("invalid metric name %q", c.Name)
+	}
+	expr, err := parse(c.Expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", c.Expr, err)
+	}
+	help := c.Help
+	if help == "" {
+		help = "Derived from " + c.Expr
+	}
+	return &Rule{name: c.Name, help: help, expr: expr}, nil
+}
+
+func (r *Rule) eval(ctx *evalContext) *io_prometheus_client.MetricFamily {
+	v := r.expr.eval(ctx)
+	if !v.isVector {
+		v.vector = map[string]sample{"": {value: v.scalar}}
+	}
+	mf := &io_prometheus_client.MetricFamily{
+		Name: new(r.name),
+		Help: new(r.help),
+		Type: io_prometheus_client.MetricType_GAUGE.Enum(),
+	}
+	for _, key := range slices.Sorted(maps.Keys(v.vector)) {
+		s := v.vector[key]
+		if math.IsNaN(s.value) || math.IsInf(s.value, 0) {
+			continue
+		}
+		mf.Metric = append(mf.Metric, &io_prometheus_client.Metric{
+			Label: s.labels,
+			Gauge: &io_prometheus_client.Gauge{Value: new(s.value)},
+		})
+	}
+	return mf
+}
+
+// Rules are derived metrics evaluated in order, so a rule can refer to the derived metrics of the preceding rules
+type Rules struct {
+	rules []*Rule
+	// tracked are the names of the metric families used by rate and deriv, whose values are kept for the next snapshot
+	tracked []string
+	now     func() time.Time
+
+	mu         sync.Mutex
+	previous   map[string]map[string]float64
+	previousAt time.Time
+}
+
+// Compile compiles the derived metrics of the configuration
+func Compile(configs []Config) (*Rules, error) {
+	rules := &Rules{now: time.Now}
+	for i, c := range configs {
+		r, err := c.Compile()
+		if err != nil {
+			return nil, fmt.Errorf("derived metric #%d: %w", i+1, err)
+		}
+		if slices.ContainsFunc(rules.rules, func(other *Rule) bool { return other.name == r.name }) {
+			return nil, fmt.Errorf("derived metric #%d: duplicate name %q", i+1, r.name)
+		}
+		visitSelectors(r.expr, func(sel *selectorNode) {
+			if sel.function != "" && !slices.Contains(rules.tracked, sel.name) {
+				rules.tracked = append(rules.tracked, sel.name)
+			}
+		})
+		rules.rules = append(rules.rules, r)
+	}
+	return rules, nil
+}
+
+// Transform appends the derived metrics to the metric families. Derived metrics without series (e.g. rates before
+// the second snapshot), and derived metrics whose name is already used by syslog-ng are omitted.
+func (rules *Rules) Transform(mfs []*io_prometheus_client.MetricFamily) []*io_prometheus_client.MetricFamily {
+	if len(rules.rules) == 0 || len(mfs) == 0 {
+		return mfs
+	}
+	rules.mu.Lock()
+	defer rules.mu.Unlock()
+
+	now := rules.now()
+	ctx := &evalContext{
+		families: make(map[string]*io_prometheus_client.MetricFamily, len(mfs)+len(rules.rules)),
+		previous: rules.previous,
+	}
+	if !rules.previousAt.IsZero() {
+		ctx.elapsed = now.Sub(rules.previousAt).Seconds()
+	}
+	for _, mf := range mfs {
+		ctx.families[mf.GetName()] = mf
+	}
+
+	for _, r := range rules.rules {
+		if ctx.families[r.name] != nil {
+			continue
+		}
+		if mf := r.eval(ctx); len(mf.Metric) > 0 {
+			ctx.families[r.name] = mf
+			mfs = append(mfs, mf)
+		}
+	}
+
+	rules.previous = make(map[string]map[string]float64, len(rules.tracked))
+	for _, name := range rules.tracked {
+		mf := ctx.families[name]
+		values := make(map[string]float64, len(mf.GetMetric()))
+		for _, m := range mf.GetMetric() {
+			if v, ok := sampleValue(mf.GetType(), m); ok {
+				values[labelSetKey(sortedLabels(m.GetLabel()))] = v
+			}
+		}
+		rules.previous[name] = values
+	}
+	rules.previousAt = now
+	return mfs
+}
@@ -0,0 +1,110 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package derived
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/internal/metrictest"
+)
+
+const (
+	before = `# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{id="d1",result="delivered"} 100
+syslogng_output_events_total{id="d1",result="dropped"} 0
+syslogng_output_events_total{id="d1",result="queued"} 5
+syslogng_output_events_total{id="d2",result="delivered"} 50
+syslogng_output_events_total{id="d2",result="dropped"} 10
+syslogng_output_events_total{id="d2",result="queued"} 0
+# TYPE syslogng_input_events_total counter
+syslogng_input_events_total{id="s1",result="processed"} 200
+`
+	// d2 was reset by a reload
+	after = `# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{id="d1",result="delivered"} 190
+syslogng_output_events_total{id="d1",result="dropped"} 10
+syslogng_output_events_total{id="d1",result="queued"} 25
+syslogng_output_events_total{id="d2",result="delivered"} 30
+syslogng_output_events_total{id="d2",result="dropped"} 0
+syslogng_output_events_total{id="d2",result="queued"} 0
+# TYPE syslogng_input_events_total counter
+syslogng_input_events_total{id="s1",result="processed"} 320
+`
+)
+
+func TestRulesTransform(t *testing.T) {
+	configs := append(slices.Clone(BuiltinRules),
+		Config{
+			Name: "syslogng_output_input_balance",
+			Expr: `sum(rate(syslogng_output_events_total{result="delivered"})) / sum(rate(syslogng_input_events_total))`,
+		},
+		Config{
+			Name: "syslogng_output_max_drop_ratio",
+			Expr: `max(syslogng_output_drop_ratio)`,
+		},
+		Config{
+			Name: "syslogng_queued_destinations_doubled",
+			Expr: `count without (id) (syslogng_output_events_total{result="queued",id!~"x.*"}) * 2`,
+		},
+	)
+	rules, err := Compile(configs)
+	require.NoError(t, err)
+	now := time.Now()
+	rules.now = func() time.Time { return now }
+
+	assert.Equal(t, metrictest.SortLines(`syslogng_queued_destinations_doubled{result="queued"} 4
+`), metrictest.Samples(rules.Transform(metrictest.Parse(t, before)), "syslogng_input_events_total", "syslogng_output_events_total"))
+
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, metrictest.SortLines(`syslogng_output_drop_ratio{id="d1"} 0.1
+syslogng_output_drop_ratio{id="d2"} 0
+syslogng_output_input_balance 1
+syslogng_output_max_drop_ratio 0.1
+syslogng_output_queue_growth_per_second{id="d1"} 2
+syslogng_output_queue_growth_per_second{id="d2"} 0
+syslogng_queued_destinations_doubled{result="queued"} 4
+`), metrictest.Samples(rules.Transform(metrictest.Parse(t, after)), "syslogng_input_events_total", "syslogng_output_events_total"))
+
+	assert.Empty(t, rules.Transform(nil), "a failed query keeps the previous snapshot")
+	now = now.Add(10 * time.Second)
+	assert.Contains(t, metrictest.Samples(rules.Transform(metrictest.Parse(t, after))), "syslogng_output_queue_growth_per_second{id=\"d1\"} 0\n")
+}
+
+func TestCompileErrors(t *testing.T) {
+	for name, c := range map[string]Config{
+		"invalid name":       {Name: "0", Expr: "1"},
+		"empty expression":   {Name: "a", Expr: ""},
+		"unbalanced":         {Name: "a", Expr: "sum(rate(a)"},
+		"rate of expression": {Name: "a", Expr: "rate(a + b)"},
+		"invalid matcher":    {Name: "a", Expr: `a{b~"c"}`},
+		"unquoted value":     {Name: "a", Expr: `a{b="c}`},
+		"invalid regex":      {Name: "a", Expr: `a{b=~"("}`},
+		"trailing tokens":    {Name: "a", Expr: `a b`},
+		"unknown character":  {Name: "a", Expr: `a % b`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Compile([]Config{c})
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := Compile([]Config{{Name: "a", Expr: "1"}, {Name: "a", Expr: "2"}})
+	assert.ErrorContains(t, err, "duplicate")
+}
@@ -0,0 +1,233 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package derived
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+)
+
+type node interface {
+	eval(ctx *evalContext) value
+}
+
+type sample struct {
+	labels []*io_prometheus_client.LabelPair // sorted by name
+	value  float64
+}
+
+// value is either a scalar or a vector of samples keyed by their label set
+type value struct {
+	scalar   float64
+	vector   map[string]sample
+	isVector bool
+}
+
+type evalContext struct {
+	families map[string]*io_prometheus_client.MetricFamily
+	// previous holds the values of the series used by rate and deriv in the previous snapshot by family and label set
+	previous map[string]map[string]float64
+	// elapsed is the time since the previous snapshot in seconds, 0 if there is none
+	elapsed float64
+}
+
+type numberNode float64
+
+func (n numberNode) eval(*evalContext) value {
+	return value{scalar: float64(n)}
+}
+
+type selectorNode struct {
+	Selector
+	// function is rate, deriv or empty for the current value
+	function string
+}
+
+func (n *selectorNode) eval(ctx *evalContext) value {
+	res := value{vector: make(map[string]sample), isVector: true}
+	mf := ctx.families[n.name]
+	for _, m := range mf.GetMetric() {
+		v, ok := sampleValue(mf.GetType(), m)
+		if !ok || !n.Matches(n.name, m.GetLabel()) {
+			continue
+		}
+		labels := sortedLabels(m.GetLabel())
+		key := labelSetKey(labels)
+		if n.function != "" {
+			prev, found := ctx.previous[n.name][key]
+			if !found || ctx.elapsed <= 0 {
+				continue
+			}
+			delta := v - prev
+			if n.function == "rate" && delta < 0 {
+				// the counter was reset by a reload or restart of syslog-ng, it has counted v since then
+				delta = v
+			}
+			v = delta / ctx.elapsed
+		}
+		res.vector[key] = sample{labels: labels, value: v}
+	}
+	return res
+}
+
+type binaryNode struct {
+	op       string
+	lhs, rhs node
+}
+
+func (n *binaryNode) eval(ctx *evalContext) value {
+	lhs, rhs := n.lhs.eval(ctx), n.rhs.eval(ctx)
+	switch {
+	case !lhs.isVector && !rhs.isVector:
+		return value{scalar: n.apply(lhs.scalar, rhs.scalar)}
+	case !rhs.isVector:
+		for key, s := range lhs.vector {
+			lhs.vector[key] = sample{labels: s.labels, value: n.apply(s.value, rhs.scalar)}
+		}
+		return lhs
+	case !lhs.isVector:
+		for key, s := range rhs.vector {
+			rhs.vector[key] = sample{labels: s.labels, value: n.apply(lhs.scalar, s.value)}
+		}
+		return rhs
+	default:
+		// one-to-one matching on the whole label set, use aggregations to align the labels of the sides
+		res := value{vector: make(map[string]sample), isVector: true}
+		for key, l := range lhs.vector {
+			if r, found := rhs.vector[key]; found {
+				res.vector[key] = sample{labels: l.labels, value: n.apply(l.value, r.value)}
+			}
+		}
+		return res
+	}
+}
+
+func (n *binaryNode) apply(lhs, rhs float64) float64 {
+	switch n.op {
+	case "+":
+		return lhs + rhs
+	case "-":
+		return lhs - rhs
+	case "*":
+		return lhs * rhs
+	default:
+		return lhs / rhs
+	}
+}
+
+var aggregations = map[string]func(values []float64) float64{
+	"sum": func(values []float64) float64 {
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum
+	},
+	"min": func(values []float64) float64 { return slices.Min(values) },
+	"max": func(values []float64) float64 { return slices.Max(values) },
+	"avg": func(values []float64) float64 {
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	},
+	"count": func(values []float64) float64 { return float64(len(values)) },
+}
+
+type aggregateNode struct {
+	op      string
+	labels  []string
+	without bool
+	expr    node
+}
+
+func (n *aggregateNode) eval(ctx *evalContext) value {
+	v := n.expr.eval(ctx)
+	if !v.isVector {
+		return v
+	}
+
+	type group struct {
+		labels []*io_prometheus_client.LabelPair
+		values []float64
+	}
+	groups := make(map[string]*group)
+	for _, s := range v.vector {
+		labels := slices.DeleteFunc(slices.Clone(s.labels), func(l *io_prometheus_client.LabelPair) bool {
+			return slices.Contains(n.labels, l.GetName()) == n.without
+		})
+		key := labelSetKey(labels)
+		g := groups[key]
+		if g == nil {
+			g = &group{labels: labels}
+			groups[key] = g
+		}
+		g.values = append(g.values, s.value)
+	}
+
+	res := value{vector: make(map[string]sample, len(groups)), isVector: true}
+	for key, g := range groups {
+		res.vector[key] = sample{labels: g.labels, value: aggregations[n.op](g.values)}
+	}
+	return res
+}
+
+// visitSelectors calls fn with every selector of the expression
+func visitSelectors(n node, fn func(*selectorNode)) {
+	switch n := n.(type) {
+	case *selectorNode:
+		fn(n)
+	case *binaryNode:
+		visitSelectors(n.lhs, fn)
+		visitSelectors(n.rhs, fn)
+	case *aggregateNode:
+		visitSelectors(n.expr, fn)
+	}
+}
+
+func sampleValue(typ io_prometheus_client.MetricType, m *io_prometheus_client.Metric) (float64, bool) {
+	switch typ {
+	case io_prometheus_client.MetricType_COUNTER:
+		return m.GetCounter().GetValue(), m.Counter != nil
+	case io_prometheus_client.MetricType_GAUGE:
+		return m.GetGauge().GetValue(), m.Gauge != nil
+	case io_prometheus_client.MetricType_UNTYPED:
+		return m.GetUntyped().GetValue(), m.Untyped != nil
+	default:
+		return math.NaN(), false
+	}
+}
+
+func sortedLabels(labels []*io_prometheus_client.LabelPair) []*io_prometheus_client.LabelPair {
+	return slices.SortedFunc(slices.Values(labels), func(a, b *io_prometheus_client.LabelPair) int {
+		return cmp.Compare(a.GetName(), b.GetName())
+	})
+}
+
+func labelSetKey(labels []*io_prometheus_client.LabelPair) string {
+	var sb strings.Builder
+	for _, l := range labels {
+		sb.WriteString(l.GetName())
+		sb.WriteByte(0xff)
+		sb.WriteString(l.GetValue())
+		sb.WriteByte(0xff)
+	}
+	return sb.String()
+}
@@ -0,0 +1,331 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package derived
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenPunct
+)
+
+// punctuation is ordered so that the two-character operators are matched first
+var punctuation = []string{"!=", "=~", "!~", "(", ")", "{", "}", ",", "+", "-", "*", "/", "="}
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || c == ':' || unicode.IsLetter(c):
+			start := i
+			for i < len(expr) && (expr[i] == '_' || expr[i] == ':' || unicode.IsLetter(rune(expr[i])) || unicode.IsDigit(rune(expr[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: expr[start:i], pos: start})
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(expr) && (unicode.IsDigit(rune(expr[i])) || expr[i] == '.' || expr[i] == 'e' || expr[i] == 'E' ||
+				((expr[i] == '+' || expr[i] == '-') && (expr[i-1] == 'e' || expr[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: expr[start:i], pos: start})
+		case c == '`':
+			end := strings.IndexByte(expr[i+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("position %d: unterminated string", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: expr[i+1 : i+1+end], pos: i})
+			i += end + 2
+		case c == '"':
+			start := i
+			for i++; i < len(expr) && expr[i] != '"'; i++ {
+				if expr[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(expr) {
+				return nil, fmt.Errorf("position %d: unterminated string", start)
+			}
+			i++
+			s, err := strconv.Unquote(expr[start:i])
+			if err != nil {
+				return nil, fmt.Errorf("position %d: invalid string: %w", start, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: s, pos: start})
+		default:
+			n := slices.IndexFunc(punctuation, func(op string) bool { return strings.HasPrefix(expr[i:], op) })
+			if n < 0 {
+				return nil, fmt.Errorf("position %d: unexpected character %q", i, c)
+			}
+			tokens = append(tokens, token{kind: tokenPunct, text: punctuation[n], pos: i})
+			i += len(punctuation[n])
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(expr)}), nil
+}
+
+// parser is a recursive descent parser of the expressions:
+//
+//	expr      = term { ("+" | "-") term }
+//	term      = unary { ("*" | "/") unary }
+//	unary     = [ "-" ] primary
+//	primary   = number | "(" expr ")" | aggregate | function | selector
+//	aggregate = ("sum" | "min" | "max" | "avg" | "count") [ ("by" | "without") labels ] "(" expr ")"
+//	function  = ("rate" | "deriv") "(" selector ")"
+//	selector  = name [ "{" [ matcher { "," matcher } ] "}" ]
+//	matcher   = label ("=" | "!=" | "=~" | "!~") string
+//
+// Strings are double or backtick quoted.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parse(expr string) (node, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.unexpected(t)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isPunct(text string) bool {
+	t := p.peek()
+	return t.kind == tokenPunct && t.text == text
+}
+
+func (p *parser) expect(text string) error {
+	if t := p.next(); t.kind != tokenPunct || t.text != text {
+		return fmt.Errorf("position %d: expected %q, found %s", t.pos, text, t)
+	}
+	return nil
+}
+
+func (p *parser) unexpected(t token) error {
+	return fmt.Errorf("position %d: unexpected %s", t.pos, t)
+}
+
+func (p *parser) expr() (node, error) {
+	lhs, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.isPunct("+") || p.isPunct("-") {
+		op := p.next().text
+		rhs, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &binaryNode{op: op, lhs: lhs, rhs: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) term() (node, error) {
+	lhs, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isPunct("*") || p.isPunct("/") {
+		op := p.next().text
+		rhs, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &binaryNode{op: op, lhs: lhs, rhs: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.isPunct("-") {
+		p.next()
+		n, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: "*", lhs: numberNode(-1), rhs: n}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokenNumber:
+		p.next()
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("position %d: invalid number %q", t.pos, t.text)
+		}
+		return numberNode(v), nil
+	case p.isPunct("("):
+		p.next()
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return n, p.expect(")")
+	case t.kind == tokenIdent:
+		switch next := p.tokens[p.pos+1]; {
+		case aggregations[t.text] != nil && (next.text == "(" || next.text == "by" || next.text == "without"):
+			return p.aggregate()
+		case (t.text == "rate" || t.text == "deriv") && next.text == "(":
+			p.next()
+			p.next()
+			sel, err := p.selector(false)
+			if err != nil {
+				return nil, err
+			}
+			return &selectorNode{Selector: *sel, function: t.text}, p.expect(")")
+		}
+		sel, err := p.selector(false)
+		if err != nil {
+			return nil, err
+		}
+		return &selectorNode{Selector: *sel}, nil
+	default:
+		return nil, p.unexpected(t)
+	}
+}
+
+func (p *parser) aggregate() (node, error) {
+	n := &aggregateNode{op: p.next().text}
+	if t := p.peek(); t.kind == tokenIdent && (t.text == "by" || t.text == "without") {
+		p.next()
+		n.without = t.text == "without"
+		labels, err := p.labels()
+		if err != nil {
+			return nil, err
+		}
+		n.labels = labels
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var err error
+	if n.expr, err = p.expr(); err != nil {
+		return nil, err
+	}
+	return n, p.expect(")")
+}
+
+func (p *parser) labels() ([]string, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var labels []string
+	for !p.isPunct(")") {
+		if len(labels) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		t := p.next()
+		if t.kind != tokenIdent {
+			return nil, fmt.Errorf("position %d: expected label name, found %s", t.pos, t)
+		}
+		labels = append(labels, t.text)
+	}
+	p.next()
+	return labels, nil
+}
+
+// selector parses a selector, the metric name is optional only if nameOptional is set and label matchers follow
+func (p *parser) selector(nameOptional bool) (*Selector, error) {
+	n := &Selector{}
+	if t := p.peek(); t.kind == tokenIdent {
+		n.name = p.next().text
+	} else if !nameOptional || !p.isPunct("{") {
+		return nil, fmt.Errorf("position %d: expected metric name, found %s", t.pos, t)
+	}
+	if !p.isPunct("{") {
+		return n, nil
+	}
+	p.next()
+	for !p.isPunct("}") {
+		if len(n.matchers) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		label := p.next()
+		if label.kind != tokenIdent {
+			return nil, fmt.Errorf("position %d: expected label name, found %s", label.pos, label)
+		}
+		op := p.next()
+		if op.kind != tokenPunct || (op.text != "=" && op.text != "!=" && op.text != "=~" && op.text != "!~") {
+			return nil, fmt.Errorf("position %d: expected label matcher operator, found %s", op.pos, op)
+		}
+		value := p.next()
+		if value.kind != tokenString {
+			return nil, fmt.Errorf("position %d: expected quoted label value, found %s", value.pos, value)
+		}
+		m, err := NewMatcher(label.text, op.text, value.text)
+		if err != nil {
+			return nil, fmt.Errorf("position %d: %w", value.pos, err)
+		}
+		n.matchers = append(n.matchers, m)
+	}
+	p.next()
+	return n, nil
+}
@@ -0,0 +1,117 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package derived
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+)
+
+// Matcher matches the value of a label like the label matchers of PromQL, missing labels are empty
+type Matcher struct {
+	label string
+	op    string
+	value string
+	regex *regexp.Regexp
+}
+
+// NewMatcher returns the matcher of the label, the operator is one of =, !=, =~ and !~. Regular expressions match the
+// whole value.
+func NewMatcher(label, op, value string) (Matcher, error) {
+	m := Matcher{label: label, op: op, value: value}
+	switch op {
+	case "=", "!=":
+	case "=~", "!~":
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return Matcher{}, fmt.Errorf("invalid regex %q: %w", value, err)
+		}
+		m.regex = re
+	default:
+		return Matcher{}, fmt.Errorf("invalid label matcher operator %q", op)
+	}
+	return m, nil
+}
+
+func (m Matcher) matches(v string) bool {
+	switch m.op {
+	case "=":
+		return v == m.value
+	case "!=":
+		return v != m.value
+	case "=~":
+		return m.regex.MatchString(v)
+	default:
+		return !m.regex.MatchString(v)
+	}
+}
+
+// Selector is a PromQL series selector, e.g. syslogng_output_events_total{result="dropped"} or
+// {__name__=~"syslogng_output_.*"}. The metric name is matched as the __name__ label.
+type Selector struct {
+	// name is the metric name, empty if the selector has only label matchers
+	name     string
+	matchers []Matcher
+}
+
+// NewSelector returns the selector of the series of the metric (any metric if empty) matching every matcher
+func NewSelector(name string, matchers ...Matcher) *Selector {
+	return &Selector{name: name, matchers: matchers}
+}
+
+// ParseSelector parses a series selector: an optional metric name followed by optional label matchers in braces,
+// with double or backtick quoted values
+func ParseSelector(s string) (*Selector, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", s, err)
+	}
+	p := &parser{tokens: tokens}
+	sel, err := p.selector(true)
+	if err == nil {
+		if t := p.peek(); t.kind != tokenEOF {
+			err = p.unexpected(t)
+		} else if sel.name == "" && len(sel.matchers) == 0 {
+			err = fmt.Errorf("empty selector")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", s, err)
+	}
+	return sel, nil
+}
+
+// Matches tells whether the series of the metric family with the name and the labels is selected
+func (s *Selector) Matches(name string, labels []*io_prometheus_client.LabelPair) bool {
+	if s.name != "" && s.name != name {
+		return false
+	}
+	for _, m := range s.matchers {
+		var v string
+		if m.label == model.MetricNameLabel {
+			v = name
+		} else if i := slices.IndexFunc(labels, func(l *io_prometheus_client.LabelPair) bool { return l.GetName() == m.label }); i >= 0 {
+			v = labels[i].GetValue()
+		}
+		if !m.matches(v) {
+			return false
+		}
+	}
+	return true
+}
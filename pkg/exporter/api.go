@@ -0,0 +1,248 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/derived"
+)
+
+// MetricsResponse is the response of the JSON metrics API. Numbers are encoded as strings like in the HTTP API of
+// Prometheus, so that NaN and infinite values are representable.
+type MetricsResponse struct {
+	// Error is the error of querying syslog-ng, the metric families describing the failure (e.g. syslogng_up) are
+	// returned anyway
+	Error   string              `json:"error,omitempty"`
+	Metrics []MetricFamilyEntry `json:"metrics"`
+}
+
+// MetricFamilyEntry is a metric family of the JSON metrics API
+type MetricFamilyEntry struct {
+	Name string `json:"name"`
+	// Type is counter, gauge, histogram, summary or untyped
+	Type   string        `json:"type"`
+	Help   string        `json:"help"`
+	Unit   string        `json:"unit,omitempty"`
+	Series []SeriesEntry `json:"series"`
+}
+
+// SeriesEntry is a series of the JSON metrics API. Counters, gauges and untyped metrics have a value, histograms
+// have buckets, summaries have quantiles, and both of them have a count and a sum.
+type SeriesEntry struct {
+	Labels map[string]string `json:"labels"`
+	// Timestamp is the time of the sample if known, otherwise the time of the query, in RFC 3339 format
+	Timestamp string          `json:"timestamp"`
+	Value     string          `json:"value,omitempty"`
+	Count     string          `json:"count,omitempty"`
+	Sum       string          `json:"sum,omitempty"`
+	Buckets   []BucketEntry   `json:"buckets,omitempty"`
+	Quantiles []QuantileEntry `json:"quantiles,omitempty"`
+}
+
+// BucketEntry is a cumulative histogram bucket
+type BucketEntry struct {
+	UpperBound string `json:"le"`
+	Count      string `json:"count"`
+}
+
+// QuantileEntry is a summary quantile
+type QuantileEntry struct {
+	Quantile string `json:"quantile"`
+	Value    string `json:"value"`
+}
+
+// MetricsFilter selects the series of the JSON metrics API. A nil filter selects every series.
+type MetricsFilter struct {
+	names []*regexp.Regexp
+	// labels selects the series matching every label matcher
+	labels *derived.Selector
+}
+
+// ParseMetricsFilter parses the filter from the query parameters: name (regular expressions matching the whole name
+// of the metric family, any of them must match) and label (name=value, name!=value, name=~regex or name!~regex,
+// all of them must match, missing labels are empty)
+func ParseMetricsFilter(query url.Values) (*MetricsFilter, error) {
+	labels, err := parseLabelMatchers(query["label"])
+	if err != nil {
+		return nil, err
+	}
+	f := &MetricsFilter{labels: labels}
+	for _, name := range query["name"] {
+		re, err := regexp.Compile("^(?:" + name + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid name %q: %w", name, err)
+		}
+		f.names = append(f.names, re)
+	}
+	return f, nil
+}
+
+// parseLabelMatchers parses the unquoted label filters into the selector of the series matching all of them
+func parseLabelMatchers(filters []string) (*derived.Selector, error) {
+	var matchers []derived.Matcher
+	for _, s := range filters {
+		i := strings.IndexAny(s, "=!")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid label filter %q, must be name=value, name!=value, name=~regex or name!~regex", s)
+		}
+		name, op, value := s[:i], "", ""
+		for _, o := range []string{"=~", "!~", "!=", "="} {
+			if strings.HasPrefix(s[i:], o) {
+				op, value = o, s[i+len(o):]
+				break
+			}
+		}
+		if op == "" || !model.LabelName(name).IsValidLegacy() {
+			return nil, fmt.Errorf("invalid label filter %q, must be name=value, name!=value, name=~regex or name!~regex", s)
+		}
+		m, err := derived.NewMatcher(name, op, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label filter %q: %w", s, err)
+		}
+		matchers = append(matchers, m)
+	}
+	return derived.NewSelector("", matchers...), nil
+}
+
+func (f *MetricsFilter) matchesName(name string) bool {
+	if f == nil || len(f.names) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(f.names, func(re *regexp.Regexp) bool { return re.MatchString(name) })
+}
+
+func (f *MetricsFilter) matchesLabels(name string, labels []*io_prometheus_client.LabelPair) bool {
+	return f == nil || f.labels.Matches(name, labels)
+}
+
+// NewMetricsResponse converts the metric families to the response of the JSON metrics API, ordered by name.
+// Series without timestamp get the time of the query.
+func NewMetricsResponse(mfs []*io_prometheus_client.MetricFamily, queryErr error, filter *MetricsFilter, now time.Time) MetricsResponse {
+	resp := MetricsResponse{Metrics: []MetricFamilyEntry{}}
+	if queryErr != nil {
+		resp.Error = queryErr.Error()
+	}
+	for _, mf := range mfs {
+		if !filter.matchesName(mf.GetName()) {
+			continue
+		}
+		family := MetricFamilyEntry{
+			Name: mf.GetName(),
+			Type: strings.ToLower(mf.GetType().String()),
+			Help: mf.GetHelp(),
+			Unit: mf.GetUnit(),
+		}
+		for _, m := range mf.GetMetric() {
+			if !filter.matchesLabels(mf.GetName(), m.GetLabel()) {
+				continue
+			}
+			labels := make(map[string]string, len(m.GetLabel()))
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			ts := now
+			if m.TimestampMs != nil {
+				ts = time.UnixMilli(m.GetTimestampMs())
+			}
+			series := SeriesEntry{Labels: labels, Timestamp: ts.UTC().Format(time.RFC3339Nano)}
+			switch mf.GetType() {
+			case io_prometheus_client.MetricType_COUNTER:
+				series.Value = formatJSONFloat(m.GetCounter().GetValue())
+			case io_prometheus_client.MetricType_GAUGE:
+				series.Value = formatJSONFloat(m.GetGauge().GetValue())
+			case io_prometheus_client.MetricType_UNTYPED:
+				series.Value = formatJSONFloat(m.GetUntyped().GetValue())
+			case io_prometheus_client.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				series.Count = strconv.FormatUint(h.GetSampleCount(), 10)
+				series.Sum = formatJSONFloat(h.GetSampleSum())
+				for _, b := range h.GetBucket() {
+					series.Buckets = append(series.Buckets, BucketEntry{
+						UpperBound: formatJSONFloat(b.GetUpperBound()),
+						Count:      strconv.FormatUint(b.GetCumulativeCount(), 10),
+					})
+				}
+			case io_prometheus_client.MetricType_SUMMARY:
+				s := m.GetSummary()
+				series.Count = strconv.FormatUint(s.GetSampleCount(), 10)
+				series.Sum = formatJSONFloat(s.GetSampleSum())
+				for _, q := range s.GetQuantile() {
+					series.Quantiles = append(series.Quantiles, QuantileEntry{
+						Quantile: formatJSONFloat(q.GetQuantile()),
+						Value:    formatJSONFloat(q.GetValue()),
+					})
+				}
+			}
+			family.Series = append(family.Series, series)
+		}
+		if len(family.Series) > 0 {
+			resp.Metrics = append(resp.Metrics, family)
+		}
+	}
+	slices.SortFunc(resp.Metrics, func(a, b MetricFamilyEntry) int { return strings.Compare(a.Name, b.Name) })
+	return resp
+}
+
+// WriteJSON writes the metric families as the response of the JSON metrics API
+func WriteJSON(w io.Writer, mfs []*io_prometheus_client.MetricFamily, queryErr error, filter *MetricsFilter, now time.Time) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(NewMetricsResponse(mfs, queryErr, filter, now))
+}
+
+func formatJSONFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// APIHandler serves the metrics that the handler serves as JSON, filtered by the name and label query parameters,
+// see ParseMetricsFilter
+func (e *Exporter) APIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger := e.logger.With("remote", r.RemoteAddr, "userAgent", r.UserAgent(), "path", r.URL.Path)
+
+		filter, err := ParseMetricsFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// the API clients are different scrapers than the Prometheus scrapers of the same host
+		mfs, queryErr := e.gatherAll(r.Context(), "api:"+e.scraperID(r), logger)
+		w.Header().Set("Content-Type", "application/json")
+		if err := WriteJSON(w, mfs, queryErr, filter, time.Now()); err != nil {
+			logger.Error("writing response failed", "error", err)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+func TestNewMetricsResponse(t *testing.T) {
+	mfs := []*io_prometheus_client.MetricFamily{
+		{
+			Name: new("syslogng_output_events_total"),
+			Help: new("Number of messages delivered, dropped or queued by the destination."),
+			Type: io_prometheus_client.MetricType_COUNTER.Enum(),
+			Metric: []*io_prometheus_client.Metric{
+				{Label: labelPairs("id", "d1", "result", "delivered"), Counter: &io_prometheus_client.Counter{Value: new(10.0)}},
+				{Label: labelPairs("id", "d2", "result", "delivered"), Counter: &io_prometheus_client.Counter{Value: new(20.0)}},
+			},
+		},
+		{
+			Name: new("syslogng_output_event_delay_sample_seconds"),
+			Type: io_prometheus_client.MetricType_GAUGE.Enum(),
+			Unit: new("seconds"),
+			Metric: []*io_prometheus_client.Metric{
+				{Label: labelPairs("id", "d1"), Gauge: &io_prometheus_client.Gauge{Value: new(math.NaN())}, TimestampMs: new(int64(1700000000000))},
+			},
+		},
+		{
+			Name: new("syslogng_output_event_delay_seconds"),
+			Type: io_prometheus_client.MetricType_HISTOGRAM.Enum(),
+			Metric: []*io_prometheus_client.Metric{
+				{Label: labelPairs("id", "d1"), Histogram: &io_prometheus_client.Histogram{
+					SampleCount: new(uint64(3)),
+					SampleSum:   new(1.5),
+					Bucket: []*io_prometheus_client.Bucket{
+						{UpperBound: new(0.5), CumulativeCount: new(uint64(2))},
+						{UpperBound: new(math.Inf(1)), CumulativeCount: new(uint64(3))},
+					},
+				}},
+			},
+		},
+	}
+	now := time.Unix(1700000060, 0)
+
+	resp := NewMetricsResponse(mfs, errors.New("partial response"), nil, now)
+	dat, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"error": "partial response",
+		"metrics": [
+			{"name": "syslogng_output_event_delay_sample_seconds", "type": "gauge", "help": "", "unit": "seconds", "series": [
+				{"labels": {"id": "d1"}, "timestamp": "2023-11-14T22:13:20Z", "value": "NaN"}
+			]},
+			{"name": "syslogng_output_event_delay_seconds", "type": "histogram", "help": "", "series": [
+				{"labels": {"id": "d1"}, "timestamp": "2023-11-14T22:14:20Z", "count": "3", "sum": "1.5",
+				 "buckets": [{"le": "0.5", "count": "2"}, {"le": "+Inf", "count": "3"}]}
+			]},
+			{"name": "syslogng_output_events_total", "type": "counter", "help": "Number of messages delivered, dropped or queued by the destination.", "series": [
+				{"labels": {"id": "d1", "result": "delivered"}, "timestamp": "2023-11-14T22:14:20Z", "value": "10"},
+				{"labels": {"id": "d2", "result": "delivered"}, "timestamp": "2023-11-14T22:14:20Z", "value": "20"}
+			]}
+		]
+	}`, string(dat))
+
+	filter, err := ParseMetricsFilter(url.Values{"name": {"syslogng_output_events_total", "syslogng_up"}, "label": {"id!~d[13]", "result=delivered"}})
+	require.NoError(t, err)
+	resp = NewMetricsResponse(mfs, nil, filter, now)
+	require.Len(t, resp.Metrics, 1)
+	require.Len(t, resp.Metrics[0].Series, 1)
+	assert.Equal(t, "d2", resp.Metrics[0].Series[0].Labels["id"])
+
+	filter, err = ParseMetricsFilter(url.Values{"label": {"missing="}})
+	require.NoError(t, err)
+	assert.Len(t, NewMetricsResponse(mfs, nil, filter, now).Metrics, 3, "missing labels are empty")
+
+	for _, query := range []url.Values{
+		{"name": {"("}},
+		{"label": {"id"}},
+		{"label": {"not-a-label=x"}},
+		{"label": {"id=~("}},
+	} {
+		_, err := ParseMetricsFilter(query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestAPIHandler(t *testing.T) {
+	ctl := syslogngctl.NewController(controlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		return statsPrometheusOutput, nil
+	}))
+	e := New(ctl, WithLogger(slog.New(slog.DiscardHandler)))
+
+	rec := httptest.NewRecorder()
+	e.APIHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/metrics?name=syslogng_up&name=syslogng_output_.*", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var resp MetricsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Metrics, 2)
+	assert.Equal(t, "syslogng_output_events_total", resp.Metrics[0].Name)
+	assert.Equal(t, "counter", resp.Metrics[0].Type)
+	assert.Equal(t, "3", resp.Metrics[0].Series[0].Value)
+	assert.Equal(t, "syslogng_up", resp.Metrics[1].Name)
+
+	rec = httptest.NewRecorder()
+	e.APIHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/metrics?label=id", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAPIHandlerScraper(t *testing.T) {
+	var sampleAge atomic.Int32
+	ctl := syslogngctl.NewController(controlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		return fmt.Sprintf(`syslogng_output_event_delay_sample_seconds{id="d_dest"} 2
+syslogng_output_event_delay_sample_age_seconds{id="d_dest"} %d
+`, sampleAge.Load()), nil
+	}))
+	e := New(ctl, WithLogger(slog.New(slog.DiscardHandler)))
+	e.delayWatermarks.created = time.Now().Add(-time.Minute)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), delaySampleMetricName)
+
+	// the sample taken before the scrape of Prometheus is delivered to the API client of the same host once
+	sampleAge.Store(5)
+	for _, delivered := range []bool{true, false} {
+		rec = httptest.NewRecorder()
+		e.APIHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/metrics", nil))
+		assert.Equal(t, delivered, strings.Contains(rec.Body.String(), delaySampleMetricName))
+	}
+}
+
+func labelPairs(nameValues ...string) []*io_prometheus_client.LabelPair {
+	var pairs []*io_prometheus_client.LabelPair
+	for i := 0; i < len(nameValues); i += 2 {
+		pairs = append(pairs, &io_prometheus_client.LabelPair{Name: new(nameValues[i]), Value: new(nameValues[i+1])})
+	}
+	return pairs
+}
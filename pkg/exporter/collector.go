@@ -0,0 +1,58 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+var _ prometheus.Collector = &Exporter{}
+
+// Describe sends nothing, the Exporter is an unchecked collector as the metrics of syslog-ng depend on its configuration
+func (e *Exporter) Describe(chan<- *prometheus.Desc) {}
+
+// Collect queries syslog-ng as the CollectorScraper and sends its metrics
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	mfs, _ := e.Gather(context.Background(), CollectorScraper)
+	for _, mf := range mfs {
+		var opts []prometheus.DescOpt
+		if mf.Unit != nil {
+			opts = append(opts, prometheus.WithUnit(mf.GetUnit()))
+		}
+		desc := prometheus.V2.NewDesc(mf.GetName(), mf.GetHelp(), prometheus.UnconstrainedLabels(nil), nil, opts...)
+		for _, m := range mf.Metric {
+			ch <- collectedMetric{desc: desc, metric: m}
+		}
+	}
+}
+
+// collectedMetric is a prometheus.Metric of an already gathered metric family
+type collectedMetric struct {
+	desc   *prometheus.Desc
+	metric *io_prometheus_client.Metric
+}
+
+func (m collectedMetric) Desc() *prometheus.Desc {
+	return m.desc
+}
+
+func (m collectedMetric) Write(out *io_prometheus_client.Metric) error {
+	proto.Merge(out, m.metric)
+	return nil
+}
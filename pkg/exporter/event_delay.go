@@ -0,0 +1,265 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+const (
+	delaySampleMetricName = "syslogng_output_event_delay_sample_seconds"
+	// delayPollerScraper is the scraper identity of the poller, so that it does not steal samples from HTTP scrapers
+	delayPollerScraper = "event-delay-poller"
+	// DefaultDelaySeriesTTL is the default time after which the aggregates of a destination without new samples are
+	// forgotten
+	DefaultDelaySeriesTTL = 24 * time.Hour
+	// delaySampleTolerance is the difference of the times of two samples with the same value that are considered
+	// the same sample, as syslog-ng reports the age of the samples in whole seconds
+	delaySampleTolerance = time.Second
+)
+
+var delaySummaryQuantiles = []float64{0.5, 0.9, 0.99}
+
+// eventDelayAggregator builds a histogram and a summary of the event delay samples of each destination.
+//
+// syslog-ng only keeps the latest delay sample of a destination, so the samples are polled more frequently than
+// Prometheus scrapes the exporter, and the short spikes between two scrapes are kept in the aggregates.
+type eventDelayAggregator struct {
+	buckets []float64
+	window  time.Duration
+	// seriesTTL is the time after which the aggregates of a destination without new samples are forgotten
+	seriesTTL time.Duration
+
+	mu     sync.Mutex
+	series map[string]*eventDelaySeries
+}
+
+type eventDelaySeries struct {
+	labels  []*io_prometheus_client.LabelPair
+	created time.Time
+	// seen is the time of the last observation, last is the last observed sample
+	seen time.Time
+	last eventDelaySample
+	// bucketCounts are the non-cumulative number of observations of each bucket, the last one is +Inf
+	bucketCounts []uint64
+	count        uint64
+	sum          float64
+	// samples within the summary window for the quantiles, count and sum are cumulative as usual
+	samples []eventDelaySample
+}
+
+type eventDelaySample struct {
+	ts    time.Time
+	value float64
+}
+
+func newEventDelayAggregator(buckets []float64, window, seriesTTL time.Duration) *eventDelayAggregator {
+	return &eventDelayAggregator{
+		buckets:   buckets,
+		window:    window,
+		seriesTTL: seriesTTL,
+		series:    make(map[string]*eventDelaySeries),
+	}
+}
+
+// ParseBuckets parses a comma-separated list of increasing bucket upper bounds
+func ParseBuckets(s string) ([]float64, error) {
+	var buckets []float64
+	for field := range strings.SplitSeq(s, ",") {
+		b, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket %q: %w", field, err)
+		}
+		if len(buckets) > 0 && b <= buckets[len(buckets)-1] {
+			return nil, fmt.Errorf("buckets must be in increasing order: %v", s)
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, nil
+}
+
+// poll queries the delay samples of the instance at every interval until ctx is done
+func (a *eventDelayAggregator) poll(ctx context.Context, inst *instance, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		res := inst.query(ctx, delayPollerScraper)
+		if res.err != nil && !errors.As(res.err, new(*syslogngctl.PartialResponseError)) {
+			logger.Warn("polling event delay samples failed", "error", res.err)
+			continue
+		}
+		a.observe(res.mfs, time.Now())
+	}
+}
+
+// observe adds the delay samples found in the metric families to the aggregates
+func (a *eventDelayAggregator) observe(mfs []*io_prometheus_client.MetricFamily, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, mf := range mfs {
+		if mf.GetName() != delaySampleMetricName {
+			continue
+		}
+		for _, m := range mf.Metric {
+			key := syslogngctl.LabelSetKey(m.Label)
+			s := a.series[key]
+			if s == nil {
+				s = &eventDelaySeries{
+					labels:       m.Label,
+					created:      now,
+					bucketCounts: make([]uint64, len(a.buckets)+1),
+				}
+				a.series[key] = s
+			}
+
+			value := m.GetGauge().GetValue()
+			ts := now
+			if m.TimestampMs != nil {
+				ts = time.UnixMilli(m.GetTimestampMs())
+			}
+			if s.count > 0 && value == s.last.value && ts.Sub(s.last.ts).Abs() <= delaySampleTolerance {
+				// the sample was already observed, e.g. by a previous poll
+				continue
+			}
+			s.seen = now
+			s.last = eventDelaySample{ts: ts, value: value}
+
+			bucket, _ := slices.BinarySearch(a.buckets, value)
+			s.bucketCounts[bucket]++
+			s.count++
+			s.sum += value
+			s.samples = append(s.samples, eventDelaySample{ts: ts, value: value})
+		}
+	}
+
+	for key, s := range a.series {
+		if now.Sub(s.seen) > a.seriesTTL {
+			delete(a.series, key)
+			continue
+		}
+		s.samples = slices.DeleteFunc(s.samples, func(sample eventDelaySample) bool {
+			return now.Sub(sample.ts) > a.window
+		})
+	}
+}
+
+// MetricFamilies returns the event delay histogram and summary of each destination
+func (a *eventDelayAggregator) MetricFamilies() []*io_prometheus_client.MetricFamily {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	histogram := &io_prometheus_client.MetricFamily{
+		Name: new("syslogng_output_event_delay_seconds"),
+		Help: new("Histogram of the latency of sampled messages between their reception and their delivery."),
+		Unit: new("seconds"),
+		Type: io_prometheus_client.MetricType_HISTOGRAM.Enum(),
+	}
+	summary := &io_prometheus_client.MetricFamily{
+		Name: new("syslogng_output_event_delay_summary_seconds"),
+		Help: new(fmt.Sprintf("Quantiles of the latency of sampled messages between their reception and their delivery over the last %s.", a.window)),
+		Unit: new("seconds"),
+		Type: io_prometheus_client.MetricType_SUMMARY.Enum(),
+	}
+
+	for _, s := range a.series {
+		var cumulative uint64
+		buckets := make([]*io_prometheus_client.Bucket, 0, len(a.buckets))
+		for i, upperBound := range a.buckets {
+			cumulative += s.bucketCounts[i]
+			buckets = append(buckets, &io_prometheus_client.Bucket{
+				UpperBound:      new(upperBound),
+				CumulativeCount: new(cumulative),
+			})
+		}
+		histogram.Metric = append(histogram.Metric, &io_prometheus_client.Metric{
+			Label: cloneLabels(s.labels),
+			Histogram: &io_prometheus_client.Histogram{
+				SampleCount:      new(s.count),
+				SampleSum:        new(s.sum),
+				Bucket:           buckets,
+				CreatedTimestamp: timestamppb.New(s.created),
+			},
+		})
+
+		values := make([]float64, 0, len(s.samples))
+		for _, sample := range s.samples {
+			values = append(values, sample.value)
+		}
+		slices.Sort(values)
+		quantiles := make([]*io_prometheus_client.Quantile, 0, len(delaySummaryQuantiles))
+		for _, q := range delaySummaryQuantiles {
+			quantiles = append(quantiles, &io_prometheus_client.Quantile{
+				Quantile: new(q),
+				Value:    new(quantile(values, q)),
+			})
+		}
+		summary.Metric = append(summary.Metric, &io_prometheus_client.Metric{
+			Label: cloneLabels(s.labels),
+			Summary: &io_prometheus_client.Summary{
+				SampleCount:      new(s.count),
+				SampleSum:        new(s.sum),
+				Quantile:         quantiles,
+				CreatedTimestamp: timestamppb.New(s.created),
+			},
+		})
+	}
+
+	if len(histogram.Metric) == 0 {
+		return nil
+	}
+	return []*io_prometheus_client.MetricFamily{histogram, summary}
+}
+
+// quantile returns the q-quantile of the sorted values using the nearest-rank method, or NaN if there are no values
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+func cloneLabels(labels []*io_prometheus_client.LabelPair) []*io_prometheus_client.LabelPair {
+	res := make([]*io_prometheus_client.LabelPair, 0, len(labels))
+	for _, l := range labels {
+		res = append(res, &io_prometheus_client.LabelPair{
+			Name:  new(l.GetName()),
+			Value: new(l.GetValue()),
+		})
+	}
+	return res
+}
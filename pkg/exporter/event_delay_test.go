@@ -0,0 +1,121 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+func TestEventDelayAggregator(t *testing.T) {
+	buckets, err := ParseBuckets("1, 5,10")
+	require.NoError(t, err)
+	_, err = ParseBuckets("5,1")
+	require.Error(t, err)
+
+	delaySample := func(value float64, ts time.Time) []*io_prometheus_client.MetricFamily {
+		return []*io_prometheus_client.MetricFamily{
+			{
+				Name: new(delaySampleMetricName),
+				Type: io_prometheus_client.MetricType_GAUGE.Enum(),
+				Metric: []*io_prometheus_client.Metric{
+					{
+						Label:       []*io_prometheus_client.LabelPair{{Name: new("id"), Value: new("d_http#0")}},
+						Gauge:       &io_prometheus_client.Gauge{Value: new(value)},
+						TimestampMs: new(ts.UnixMilli()),
+					},
+				},
+			},
+		}
+	}
+
+	agg := newEventDelayAggregator(buckets, time.Minute, time.Hour)
+	assert.Empty(t, agg.MetricFamilies())
+
+	now := time.Now()
+	agg.observe(delaySample(30, now.Add(-2*time.Minute)), now)
+	agg.observe(nil, now)
+	agg.observe(delaySample(1, now), now)
+	agg.observe(delaySample(4, now), now)
+	agg.observe(delaySample(7, now), now)
+	// the same sample reported again with the age rounded to seconds
+	agg.observe(delaySample(7, now.Add(time.Second)), now.Add(time.Second))
+
+	var text strings.Builder
+	for _, mf := range agg.MetricFamilies() {
+		mf.Help = nil
+		_, err := expfmt.MetricFamilyToText(&text, mf)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, `# TYPE syslogng_output_event_delay_seconds histogram
+syslogng_output_event_delay_seconds_bucket{id="d_http#0",le="1"} 1
+syslogng_output_event_delay_seconds_bucket{id="d_http#0",le="5"} 2
+syslogng_output_event_delay_seconds_bucket{id="d_http#0",le="10"} 3
+syslogng_output_event_delay_seconds_bucket{id="d_http#0",le="+Inf"} 4
+syslogng_output_event_delay_seconds_sum{id="d_http#0"} 42
+syslogng_output_event_delay_seconds_count{id="d_http#0"} 4
+# TYPE syslogng_output_event_delay_summary_seconds summary
+syslogng_output_event_delay_summary_seconds{id="d_http#0",quantile="0.5"} 4
+syslogng_output_event_delay_summary_seconds{id="d_http#0",quantile="0.9"} 7
+syslogng_output_event_delay_summary_seconds{id="d_http#0",quantile="0.99"} 7
+syslogng_output_event_delay_summary_seconds_sum{id="d_http#0"} 42
+syslogng_output_event_delay_summary_seconds_count{id="d_http#0"} 4
+`, text.String())
+
+	// destinations without new samples are forgotten
+	agg.observe(nil, now.Add(time.Hour+time.Minute))
+	assert.Empty(t, agg.MetricFamilies())
+}
+
+func TestExporterEventDelayTransformed(t *testing.T) {
+	ctl := syslogngctl.NewController(controlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		return statsPrometheusOutput, nil
+	}))
+	var transformed []string
+	record := TransformerFunc(func(mfs []*io_prometheus_client.MetricFamily) []*io_prometheus_client.MetricFamily {
+		transformed = nil
+		for _, mf := range mfs {
+			transformed = append(transformed, mf.GetName())
+		}
+		return mfs
+	})
+	e := New(ctl, WithLogger(slog.New(slog.DiscardHandler)), WithTransformers(record),
+		WithEventDelayAggregation(time.Second, []float64{1}, time.Minute, 0))
+	assert.Equal(t, DefaultDelaySeriesTTL, e.delay.seriesTTL)
+	e.delay.observe([]*io_prometheus_client.MetricFamily{{
+		Name:   new(delaySampleMetricName),
+		Type:   io_prometheus_client.MetricType_GAUGE.Enum(),
+		Metric: []*io_prometheus_client.Metric{{Label: labelPairs("id", "d_dest"), Gauge: &io_prometheus_client.Gauge{Value: new(2.0)}}},
+	}}, time.Now())
+
+	selection, err := NewSelection(nil, []string{"syslogng_output_event_delay_summary_seconds"})
+	require.NoError(t, err)
+	mfs, err := e.gather(context.Background(), "a", e.logger, selection)
+	require.NoError(t, err)
+	assert.Contains(t, transformed, "syslogng_output_event_delay_seconds")
+	assert.Contains(t, transformed, "syslogng_output_event_delay_summary_seconds")
+	assert.True(t, hasFamily(mfs, "syslogng_output_event_delay_seconds"))
+	assert.False(t, hasFamily(mfs, "syslogng_output_event_delay_summary_seconds"))
+}
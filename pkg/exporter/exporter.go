@@ -0,0 +1,404 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package exporter exposes the metrics of syslog-ng (AxoSyslog) to Prometheus, either as a prometheus.Collector
+// that can be registered in an existing registry, or as an http.Handler serving the /metrics endpoint.
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+const (
+	// DefaultTimeout is the default timeout of querying syslog-ng
+	DefaultTimeout = 5 * time.Second
+	// CollectorScraper is the scraper identity used by Collect to keep track of the returned event delay samples
+	CollectorScraper = "prometheus-collector"
+)
+
+// Exporter queries the metrics of syslog-ng through a Controller (or several, see NewMultiInstance) and adds the
+// metrics describing the outcome of the queries (syslogng_up, syslogng_scrape_error, ...) and the event delay
+// aggregates, if enabled.
+//
+// The legacy counters and lenient parsing are options of the Controller, see syslogngctl.WithLegacyStats and
+// syslogngctl.WithLenientParsing.
+type Exporter struct {
+	instances  []*instance
+	created    time.Time
+	logger     *slog.Logger
+	timeout    time.Duration
+	badGateway bool
+	scraperID  ScraperIdentifier
+	gatherers  prometheus.Gatherers
+	instr      *Instrumentation
+
+	transformers   []Transformer
+	externalLabels *externalLabels
+	modules        map[string]*Selection
+
+	delay             *eventDelayAggregator
+	delayPollInterval time.Duration
+
+	snapshots       *snapshotCache
+	flights         singleflight.Group
+	delayWatermarks *delayWatermarks
+	scraperTTL      time.Duration
+
+	skipped *skippedItemsReporter
+}
+
+// Transformer modifies the metric families returned by syslog-ng before they are served.
+// The metric families are owned by the transformer, so they can be modified in place.
+type Transformer interface {
+	Transform(mfs []*io_prometheus_client.MetricFamily) []*io_prometheus_client.MetricFamily
+}
+
+// TransformerFunc is a function implementing Transformer
+type TransformerFunc func(mfs []*io_prometheus_client.MetricFamily) []*io_prometheus_client.MetricFamily
+
+func (fn TransformerFunc) Transform(mfs []*io_prometheus_client.MetricFamily) []*io_prometheus_client.MetricFamily {
+	return fn(mfs)
+}
+
+// Option is an option for New and NewMultiInstance
+type Option func(*Exporter)
+
+// WithLogger sets the logger of the exporter (default: slog.Default)
+func WithLogger(logger *slog.Logger) Option {
+	return func(e *Exporter) {
+		e.logger = logger
+	}
+}
+
+// WithTimeout sets the timeout of querying syslog-ng, the default timeout of the instances of a multi-instance
+// exporter (default: DefaultTimeout)
+func WithTimeout(timeout time.Duration) Option {
+	return func(e *Exporter) {
+		e.timeout = timeout
+	}
+}
+
+// WithBadGatewayOnError makes the handler respond with 502 Bad Gateway if querying syslog-ng fails,
+// instead of reporting the failure in syslogng_up and syslogng_scrape_error
+func WithBadGatewayOnError(enabled bool) Option {
+	return func(e *Exporter) {
+		e.badGateway = enabled
+	}
+}
+
+// WithScraperIdentity sets how the handler tells scrapers apart to deliver every event delay sample to each of them
+// (default: the IP address of the client)
+func WithScraperIdentity(identify ScraperIdentifier) Option {
+	return func(e *Exporter) {
+		e.scraperID = identify
+	}
+}
+
+// WithScraperTTL sets the time after which the event delay watermark of an inactive scraper is forgotten
+// (default: syslogngctl.DefaultScraperTTL)
+func WithScraperTTL(ttl time.Duration) Option {
+	return func(e *Exporter) {
+		e.scraperTTL = ttl
+	}
+}
+
+// WithEventDelayAggregation enables the syslogng_output_event_delay_seconds histogram and summary.
+// The delay samples are polled at the interval by Run, the buckets are the upper bounds of the histogram buckets,
+// the window is the time window of the summary quantiles, and the aggregates of a destination without new samples
+// are forgotten after the series TTL (0 means DefaultDelaySeriesTTL). The aggregates are served along with the
+// metrics of syslog-ng, and go through the same transformers and selections.
+func WithEventDelayAggregation(pollInterval time.Duration, buckets []float64, window, seriesTTL time.Duration) Option {
+	return func(e *Exporter) {
+		if seriesTTL <= 0 {
+			seriesTTL = DefaultDelaySeriesTTL
+		}
+		e.delay = newEventDelayAggregator(buckets, window, seriesTTL)
+		e.delayPollInterval = pollInterval
+	}
+}
+
+// WithSnapshotPolling makes Run query syslog-ng at the interval, and the exporter serve the last snapshot to every
+// client instead of querying syslog-ng on each scrape. If the snapshot is older than maxStaleness (e.g. polling
+// failed to keep up), it is refreshed on demand. A maxStaleness of 0 means twice the interval.
+//
+// Without snapshots, the concurrent scrapes are coalesced into a single query.
+func WithSnapshotPolling(interval time.Duration, maxStaleness time.Duration) Option {
+	return func(e *Exporter) {
+		if maxStaleness <= 0 {
+			maxStaleness = 2 * interval
+		}
+		e.snapshots = &snapshotCache{interval: interval, maxStaleness: maxStaleness}
+	}
+}
+
+// WithTransformers appends transformers applied in order to the metric families returned by syslog-ng,
+// e.g. relabel.Rules
+func WithTransformers(transformers ...Transformer) Option {
+	return func(e *Exporter) {
+		e.transformers = append(e.transformers, transformers...)
+	}
+}
+
+// WithModules sets the named selections served by the handler when requested by the module query parameter
+func WithModules(modules map[string]*Selection) Option {
+	return func(e *Exporter) {
+		e.modules = modules
+	}
+}
+
+// WithInstrumentation records the size of the scrapes in the exporter metrics
+func WithInstrumentation(instr *Instrumentation) Option {
+	return func(e *Exporter) {
+		e.instr = instr
+	}
+}
+
+// WithGatherer adds the metrics of the gatherer to the responses of the handler, e.g. Instrumentation.Registry
+func WithGatherer(g prometheus.Gatherer) Option {
+	return func(e *Exporter) {
+		e.gatherers = append(e.gatherers, g)
+	}
+}
+
+func New(ctl *syslogngctl.Controller, opts ...Option) *Exporter {
+	return newExporter([]*instance{{Instance: Instance{Controller: ctl}}}, opts...)
+}
+
+func newExporter(instances []*instance, opts ...Option) *Exporter {
+	e := &Exporter{
+		instances:  instances,
+		created:    time.Now(),
+		logger:     slog.Default(),
+		timeout:    DefaultTimeout,
+		scraperID:  remoteHost,
+		scraperTTL: syslogngctl.DefaultScraperTTL,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.delayWatermarks = newDelayWatermarks(e.created, e.scraperTTL)
+	for _, inst := range e.instances {
+		if inst.Timeout <= 0 {
+			inst.Timeout = e.timeout
+		}
+	}
+	e.skipped = newSkippedItemsReporter(skippedLogInterval, e.created)
+	return e
+}
+
+// Run polls the event delay samples and the snapshots until ctx is done, if they are enabled
+func (e *Exporter) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	if e.delay != nil {
+		e.logger.Info("polling event delay samples", "interval", e.delayPollInterval)
+		for _, inst := range e.instances {
+			wg.Go(func() {
+				e.delay.poll(ctx, inst, e.delayPollInterval, e.logger)
+			})
+		}
+	}
+	if e.snapshots != nil {
+		e.logger.Info("polling snapshots", "interval", e.snapshots.interval, "maxStaleness", e.snapshots.maxStaleness)
+		wg.Go(func() {
+			e.pollSnapshots(ctx)
+		})
+	}
+	wg.Wait()
+}
+
+// GatherFunc returns the metrics to push, e.g. Exporter.Gather of the scraper of the pusher. Errors are ignored by the
+// pushers, the returned metric families (e.g. syslogng_up) are pushed anyway.
+type GatherFunc func(ctx context.Context) ([]*io_prometheus_client.MetricFamily, error)
+
+// Gather queries the metrics of syslog-ng for the scraper, and returns them along with the metrics of the exporter.
+//
+// If querying syslog-ng fails, the error is returned along with the metric families that describe the failure,
+// and the metric families that could be parsed, if any.
+func (e *Exporter) Gather(ctx context.Context, scraper string) ([]*io_prometheus_client.MetricFamily, error) {
+	return e.gather(ctx, scraper, e.logger)
+}
+
+// gather queries the metrics of syslog-ng and returns the series selected by the selections along with the metrics
+// of the exporter
+func (e *Exporter) gather(ctx context.Context, scraper string, logger *slog.Logger, selections ...*Selection) ([]*io_prometheus_client.MetricFamily, error) {
+	var res scrapeResult
+	if e.snapshots != nil {
+		res = e.snapshot(logger)
+	} else {
+		res = e.coalescedQuery(ctx, logger)
+	}
+
+	// the scraper receives the event delay samples of the shared result that it has not received yet
+	mfs := e.delayWatermarks.filter(scraper, res.mfs, res.at)
+	for _, s := range selections {
+		mfs = s.Apply(mfs)
+	}
+	var status []*io_prometheus_client.MetricFamily
+	for i, inst := range e.instances {
+		r := res.instances[i]
+		status = mergeFamilies(status, inst.labels.apply(inst.status.metricFamilies(r.err, r.duration, res.at)))
+	}
+	mfs = append(mfs, status...)
+	mfs = append(mfs, e.metadataFallbackMetric(), e.skipped.metricFamily())
+	if e.snapshots != nil {
+		mfs = append(mfs, snapshotAgeMetric(time.Since(res.at)))
+	}
+	return e.externalLabels.apply(mfs), res.err
+}
+
+// query queries the metrics of the instances of syslog-ng concurrently for the scraper
+func (e *Exporter) query(ctx context.Context, scraper string, logger *slog.Logger) scrapeResult {
+	start := time.Now()
+	results := make([]instanceResult, len(e.instances))
+	var wg sync.WaitGroup
+	for i, inst := range e.instances {
+		wg.Go(func() {
+			results[i] = inst.query(ctx, scraper)
+		})
+	}
+	wg.Wait()
+	res := scrapeResult{instances: results, duration: time.Since(start), at: time.Now()}
+
+	var mfs []*io_prometheus_client.MetricFamily
+	var errs []error
+	for _, r := range results {
+		mfs = mergeFamilies(mfs, r.mfs)
+		var partial *syslogngctl.PartialResponseError
+		if errors.As(r.err, &partial) {
+			e.skipped.report(logger, partial, res.at)
+		} else if r.err != nil {
+			logger.Error("querying syslog-ng stats failed", "error", r.err, "class", classifyScrapeError(r.err), "metricFamilies", len(r.mfs))
+		}
+		if r.err != nil {
+			errs = append(errs, r.err)
+		}
+	}
+	// failed queries return no metric families, which the transformers ignore
+	if e.delay != nil && len(mfs) > 0 {
+		mfs = append(mfs, e.delay.MetricFamilies()...)
+	}
+	for _, t := range e.transformers {
+		mfs = t.Transform(mfs)
+	}
+	res.mfs = mfs
+	if len(errs) == 1 {
+		res.err = errs[0]
+	} else {
+		res.err = errors.Join(errs...)
+	}
+	if e.instr != nil {
+		e.instr.observeScrape(mfs)
+	}
+	return res
+}
+
+func (e *Exporter) metadataFallbackMetric() *io_prometheus_client.MetricFamily {
+	return &io_prometheus_client.MetricFamily{
+		Name: new("syslogng_exporter_metadata_fallbacks_total"),
+		Help: new("Number of metric families missing from the metrics catalog that were typed by their name."),
+		Type: io_prometheus_client.MetricType_COUNTER.Enum(),
+		Metric: []*io_prometheus_client.Metric{
+			{
+				Counter: &io_prometheus_client.Counter{
+					Value:            new(float64(e.metadataFallbacks())),
+					CreatedTimestamp: timestamppb.New(e.created),
+				},
+			},
+		},
+	}
+}
+
+// metadataFallbacks returns the number of metric families typed by their name in the metric catalogs of the instances
+func (e *Exporter) metadataFallbacks() uint64 {
+	var catalogs []*syslogngctl.MetricCatalog
+	var count uint64
+	for _, inst := range e.instances {
+		if catalog := inst.Controller.MetricCatalog(); !slices.Contains(catalogs, catalog) {
+			catalogs = append(catalogs, catalog)
+			count += catalog.FallbackCount()
+		}
+	}
+	return count
+}
+
+// gatherAll gathers the metrics along with the metrics of the additional gatherers, the returned error is the error
+// of querying syslog-ng
+func (e *Exporter) gatherAll(ctx context.Context, scraper string, logger *slog.Logger, selections ...*Selection) ([]*io_prometheus_client.MetricFamily, error) {
+	mfs, err := e.gather(ctx, scraper, logger, selections...)
+	extra, gatherErr := e.gatherers.Gather()
+	if gatherErr != nil {
+		logger.Error("gathering exporter metrics failed", "error", gatherErr)
+	}
+	return append(mfs, e.externalLabels.apply(extra)...), err
+}
+
+// ServeHTTP serves the metrics in the exposition format negotiated with the client. The series of syslog-ng can be
+// selected by the module (see WithModules) and the selectors of the query (see ParseSelection).
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := e.logger.With("remote", r.RemoteAddr, "userAgent", r.UserAgent(), "path", r.URL.Path)
+
+	query := r.URL.Query()
+	var module *Selection
+	if name := query.Get("module"); name != "" {
+		var found bool
+		if module, found = e.modules[name]; !found {
+			http.Error(w, fmt.Sprintf("unknown module %q", name), http.StatusBadRequest)
+			return
+		}
+	}
+	selection, err := ParseSelection(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mfs, err := e.gatherAll(r.Context(), selectionScraper(e.scraperID(r), query), logger, module, selection)
+	if e.badGateway && e.queryFailed(err) {
+		http.Error(w, "failed to query syslog-ng stats", http.StatusBadGateway)
+		return
+	}
+
+	var resp bytes.Buffer
+
+	format := negotiateFormat(r.Header)
+	if err := encodeMetricFamilies(&resp, format, mfs); err != nil {
+		http.Error(w, "failed to convert metrics", http.StatusInternalServerError)
+		logger.Error("metrics conversion failed", "error", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", string(format))
+	bodyLen, err := io.Copy(w, &resp)
+	if err != nil {
+		logger.Error("writing response failed", "error", err)
+		return
+	}
+	logger.Info("writing response", "bodyLength", bodyLen, "format", format)
+}
@@ -0,0 +1,144 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+const statsPrometheusOutput = `syslogng_scratch_buffers_count 2
+syslogng_output_events_total{id="d_dest",result="delivered"} 3
+`
+
+func TestExporterCollector(t *testing.T) {
+	ctl := syslogngctl.NewController(controlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		return statsPrometheusOutput, nil
+	}))
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(New(ctl, WithLogger(slog.New(slog.DiscardHandler)))))
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP syslogng_output_events_total Number of messages delivered, dropped or queued by the destination.
+# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{id="d_dest",result="delivered"} 3
+# HELP syslogng_scratch_buffers_count Number of allocated scratch buffers.
+# TYPE syslogng_scratch_buffers_count gauge
+syslogng_scratch_buffers_count 2
+# HELP syslogng_up Whether syslog-ng returned its metrics (1) or not (0).
+# TYPE syslogng_up gauge
+syslogng_up 1
+`), "syslogng_output_events_total", "syslogng_scratch_buffers_count", "syslogng_up"))
+}
+
+func TestExporterHandler(t *testing.T) {
+	var failing bool
+	ctl := syslogngctl.NewController(controlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		if failing {
+			return "", errors.New("connection refused")
+		}
+		return statsPrometheusOutput, nil
+	}))
+	extra := prometheus.NewRegistry()
+	extra.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "extra_gauge", Help: "Extra gauge."}))
+
+	scrape := func(e *Exporter) (int, string) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		body, _ := io.ReadAll(rec.Body)
+		return rec.Code, string(body)
+	}
+
+	e := New(ctl, WithLogger(slog.New(slog.DiscardHandler)), WithGatherer(extra))
+	code, body := scrape(e)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "syslogng_scratch_buffers_count 2\n")
+	assert.Contains(t, body, "syslogng_up 1\n")
+	assert.Contains(t, body, "extra_gauge 0\n")
+
+	failing = true
+	code, body = scrape(e)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "syslogng_up 0\n")
+
+	code, _ = scrape(New(ctl, WithLogger(slog.New(slog.DiscardHandler)), WithBadGatewayOnError(true)))
+	assert.Equal(t, http.StatusBadGateway, code)
+}
+
+func TestExporterHandlerMalformedResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		lenient  bool
+		expected int
+	}{
+		{
+			name:     "one skipped item",
+			output:   "syslogng_scratch_buffers_count 2\nsyslogng_input_events_total{id=\"s_src\"} 1x\n",
+			lenient:  true,
+			expected: http.StatusOK,
+		},
+		{
+			name:     "two skipped items",
+			output:   "syslogng_scratch_buffers_count 2\nsyslogng_input_events_total{id=\"s_src\"} 1x\nsyslogng_socket_connections{id=\"s_net\" 5\n",
+			lenient:  true,
+			expected: http.StatusOK,
+		},
+		{
+			name:     "strict legacy with two malformed lines",
+			output:   "SourceName;SourceId;SourceInstance;State;Type;Number\nfilter;ff;;a;matched;2\nfilter;ff;;a;not_matched\ndst.network;d_dest#0;;a;written;x\n",
+			expected: http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctl := syslogngctl.NewController(controlChannelFunc(func(_ context.Context, _ string) (string, error) {
+				return tt.output, nil
+			}), syslogngctl.WithLenientParsing(tt.lenient))
+			e := New(ctl, WithLogger(slog.New(slog.DiscardHandler)), WithBadGatewayOnError(true))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			assert.Equal(t, tt.expected, rec.Code)
+		})
+	}
+}
+
+func TestExporterTransformers(t *testing.T) {
+	ctl := syslogngctl.NewController(controlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		return statsPrometheusOutput, nil
+	}))
+	dropAll := TransformerFunc(func(mfs []*io_prometheus_client.MetricFamily) []*io_prometheus_client.MetricFamily {
+		return nil
+	})
+
+	mfs, err := New(ctl, WithLogger(slog.New(slog.DiscardHandler)), WithTransformers(dropAll)).Gather(context.Background(), "a")
+	require.NoError(t, err)
+	assert.False(t, hasFamily(mfs, "syslogng_scratch_buffers_count"))
+	assert.True(t, hasFamily(mfs, "syslogng_up"))
+}
@@ -0,0 +1,57 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// negotiateFormat returns the exposition format to use based on the Accept header of the scraper.
+// The classic text format, OpenMetrics and the delimited protobuf format are supported.
+func negotiateFormat(header http.Header) expfmt.Format {
+	format := expfmt.NegotiateIncludingOpenMetrics(header)
+	switch format.FormatType() {
+	case expfmt.TypeProtoText, expfmt.TypeProtoCompact:
+		// only meant for debugging, serve the text format instead
+		return expfmt.NewFormat(expfmt.TypeTextPlain)
+	default:
+		return format
+	}
+}
+
+// encodeMetricFamilies writes the metric families ordered by name in the specified format.
+// In case of OpenMetrics, units and _created samples are included where known, and the output is terminated by # EOF.
+func encodeMetricFamilies(w io.Writer, format expfmt.Format, mfs []*io_prometheus_client.MetricFamily) error {
+	slices.SortFunc(mfs, func(a, b *io_prometheus_client.MetricFamily) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+
+	enc := expfmt.NewEncoder(w, format, expfmt.WithCreatedLines())
+	for _, mf := range mfs {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+	if closer, ok := enc.(expfmt.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+func TestEncodeMetricFamilies(t *testing.T) {
+	e := New(syslogngctl.NewController(nil, syslogngctl.WithMetricCatalog(syslogngctl.NewMetricCatalog())))
+	e.created = time.Unix(1700000000, 0)
+	mfs := func() []*io_prometheus_client.MetricFamily {
+		return []*io_prometheus_client.MetricFamily{
+			e.metadataFallbackMetric(),
+			{
+				Name: new("syslogng_events_allocated_bytes"),
+				Help: new("Memory used by the messages currently in flight."),
+				Unit: new("bytes"),
+				Type: io_prometheus_client.MetricType_GAUGE.Enum(),
+				Metric: []*io_prometheus_client.Metric{
+					{Gauge: &io_prometheus_client.Gauge{Value: new(42.0)}},
+				},
+			},
+		}
+	}
+
+	testCases := map[string]struct {
+		accept   string
+		expected expfmt.FormatType
+		check    func(t *testing.T, body []byte)
+	}{
+		"no accept header": {
+			expected: expfmt.TypeTextPlain,
+			check: func(t *testing.T, body []byte) {
+				assert.Equal(t, "# HELP syslogng_events_allocated_bytes Memory used by the messages currently in flight.\n"+
+					"# TYPE syslogng_events_allocated_bytes gauge\n"+
+					"syslogng_events_allocated_bytes 42\n"+
+					"# HELP syslogng_exporter_metadata_fallbacks_total Number of metric families missing from the metrics catalog that were typed by their name.\n"+
+					"# TYPE syslogng_exporter_metadata_fallbacks_total counter\n"+
+					"syslogng_exporter_metadata_fallbacks_total 0\n", string(body))
+			},
+		},
+		"openmetrics": {
+			accept:   "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5",
+			expected: expfmt.TypeOpenMetrics,
+			check: func(t *testing.T, body []byte) {
+				assert.Equal(t, "# HELP syslogng_events_allocated_bytes Memory used by the messages currently in flight.\n"+
+					"# TYPE syslogng_events_allocated_bytes gauge\n"+
+					"# UNIT syslogng_events_allocated_bytes bytes\n"+
+					"syslogng_events_allocated_bytes 42.0\n"+
+					"# HELP syslogng_exporter_metadata_fallbacks Number of metric families missing from the metrics catalog that were typed by their name.\n"+
+					"# TYPE syslogng_exporter_metadata_fallbacks counter\n"+
+					"syslogng_exporter_metadata_fallbacks_total 0.0\n"+
+					"syslogng_exporter_metadata_fallbacks_created 1.7e+09\n"+
+					"# EOF\n", string(body))
+			},
+		},
+		"protobuf": {
+			accept:   "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited",
+			expected: expfmt.TypeProtoDelim,
+			check: func(t *testing.T, body []byte) {
+				dec := expfmt.NewDecoder(bytes.NewReader(body), expfmt.NewFormat(expfmt.TypeProtoDelim))
+				var mf io_prometheus_client.MetricFamily
+				require.NoError(t, dec.Decode(&mf))
+				assert.Equal(t, "syslogng_events_allocated_bytes", mf.GetName())
+				assert.Equal(t, "bytes", mf.GetUnit())
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			header := http.Header{}
+			if testCase.accept != "" {
+				header.Set("Accept", testCase.accept)
+			}
+			format := negotiateFormat(header)
+			assert.Equal(t, testCase.expected, format.FormatType())
+
+			var body bytes.Buffer
+			require.NoError(t, encodeMetricFamilies(&body, format, mfs()))
+			testCase.check(t, body.Bytes())
+		})
+	}
+}
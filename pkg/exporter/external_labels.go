@@ -0,0 +1,176 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+)
+
+// LabelCollisionPolicy tells what happens if a series already has a label with the name of an external label
+type LabelCollisionPolicy string
+
+const (
+	// CollisionRename renames the label of the series to exported_<name>, like Prometheus does without honor_labels
+	CollisionRename LabelCollisionPolicy = "rename"
+	// CollisionOverwrite replaces the label of the series with the external label
+	CollisionOverwrite LabelCollisionPolicy = "overwrite"
+	// CollisionKeep keeps the label of the series and skips the external label
+	CollisionKeep LabelCollisionPolicy = "keep"
+
+	DefaultLabelCollision = CollisionRename
+
+	exportedLabelPrefix = "exported_"
+)
+
+// ParseLabelCollisionPolicy parses the name of a LabelCollisionPolicy, an empty name means DefaultLabelCollision
+func ParseLabelCollisionPolicy(name string) (LabelCollisionPolicy, error) {
+	switch policy := LabelCollisionPolicy(name); policy {
+	case "":
+		return DefaultLabelCollision, nil
+	case CollisionRename, CollisionOverwrite, CollisionKeep:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid label collision policy %q, use rename, overwrite or keep", name)
+	}
+}
+
+// ParseExternalLabel parses an external label in name=value format
+func ParseExternalLabel(s string) (name string, value string, err error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", fmt.Errorf("invalid external label %q, use name=value", s)
+	}
+	if err := ValidateExternalLabelName(name); err != nil {
+		return "", "", err
+	}
+	return name, value, nil
+}
+
+// ValidateExternalLabelName checks that the name is a valid label name that is not reserved for internal use
+func ValidateExternalLabelName(name string) error {
+	if !model.LabelName(name).IsValidLegacy() || strings.HasPrefix(name, model.ReservedLabelPrefix) {
+		return fmt.Errorf("invalid external label name %q", name)
+	}
+	return nil
+}
+
+// WithExternalLabels attaches the labels to every series served by the exporter, including the metrics of the
+// exporter itself. The policy tells what happens if a series already has a label with the same name.
+func WithExternalLabels(labels map[string]string, onCollision LabelCollisionPolicy) Option {
+	return func(e *Exporter) {
+		if len(labels) == 0 {
+			e.externalLabels = nil
+			return
+		}
+		e.externalLabels = &externalLabels{onCollision: onCollision}
+		for _, name := range slices.Sorted(maps.Keys(labels)) {
+			e.externalLabels.labels = append(e.externalLabels.labels, &io_prometheus_client.LabelPair{
+				Name:  new(name),
+				Value: new(labels[name]),
+			})
+		}
+	}
+}
+
+type externalLabels struct {
+	labels      []*io_prometheus_client.LabelPair
+	onCollision LabelCollisionPolicy
+}
+
+// apply returns the metric families with the external labels attached. The metric families are not modified,
+// as they may be shared with other scrapes.
+func (x *externalLabels) apply(mfs []*io_prometheus_client.MetricFamily) []*io_prometheus_client.MetricFamily {
+	if x == nil {
+		return mfs
+	}
+	res := make([]*io_prometheus_client.MetricFamily, 0, len(mfs))
+	for _, mf := range mfs {
+		metrics := make([]*io_prometheus_client.Metric, 0, len(mf.GetMetric()))
+		for _, m := range mf.GetMetric() {
+			metrics = append(metrics, &io_prometheus_client.Metric{
+				Label:       x.labelsOf(m.GetLabel()),
+				Gauge:       m.Gauge,
+				Counter:     m.Counter,
+				Summary:     m.Summary,
+				Untyped:     m.Untyped,
+				Histogram:   m.Histogram,
+				TimestampMs: m.TimestampMs,
+			})
+		}
+		res = append(res, &io_prometheus_client.MetricFamily{
+			Name:   mf.Name,
+			Help:   mf.Help,
+			Type:   mf.Type,
+			Unit:   mf.Unit,
+			Metric: metrics,
+		})
+	}
+	return res
+}
+
+func (x *externalLabels) labelsOf(labels []*io_prometheus_client.LabelPair) []*io_prometheus_client.LabelPair {
+	used := make(map[string]bool, len(labels)+len(x.labels))
+	for _, l := range labels {
+		used[l.GetName()] = true
+	}
+	for _, l := range x.labels {
+		used[l.GetName()] = true
+	}
+
+	res := make([]*io_prometheus_client.LabelPair, 0, len(labels)+len(x.labels))
+	kept := make(map[string]bool)
+	for _, l := range labels {
+		if !x.has(l.GetName()) {
+			res = append(res, l)
+			continue
+		}
+		switch x.onCollision {
+		case CollisionKeep:
+			res = append(res, l)
+			kept[l.GetName()] = true
+		case CollisionOverwrite:
+		default:
+			name := exportedLabelPrefix + l.GetName()
+			for used[name] {
+				name = exportedLabelPrefix + name
+			}
+			used[name] = true
+			res = append(res, &io_prometheus_client.LabelPair{Name: new(name), Value: l.Value})
+		}
+	}
+	for _, l := range x.labels {
+		if !kept[l.GetName()] {
+			res = append(res, l)
+		}
+	}
+	slices.SortFunc(res, func(a, b *io_prometheus_client.LabelPair) int {
+		return cmp.Compare(a.GetName(), b.GetName())
+	})
+	return res
+}
+
+func (x *externalLabels) has(name string) bool {
+	_, found := slices.BinarySearchFunc(x.labels, name, func(l *io_prometheus_client.LabelPair, name string) int {
+		return cmp.Compare(l.GetName(), name)
+	})
+	return found
+}
@@ -0,0 +1,74 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+func TestParseExternalLabel(t *testing.T) {
+	name, value, err := ParseExternalLabel("cluster=eu-1=a")
+	require.NoError(t, err)
+	assert.Equal(t, "cluster", name)
+	assert.Equal(t, "eu-1=a", value)
+
+	for _, s := range []string{"cluster", "1cluster=a", "__name__=a", "=a"} {
+		_, _, err := ParseExternalLabel(s)
+		assert.Error(t, err, s)
+	}
+
+	policy, err := ParseLabelCollisionPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultLabelCollision, policy)
+	_, err = ParseLabelCollisionPolicy("ignore")
+	assert.Error(t, err)
+}
+
+func TestExporterExternalLabels(t *testing.T) {
+	ctl := syslogngctl.NewController(controlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		return `syslogng_output_events_total{id="d_dest",exported_id="x",result="delivered"} 3
+`, nil
+	}))
+	extra := prometheus.NewRegistry()
+	extra.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "extra_gauge", Help: "Extra gauge."}))
+	labels := map[string]string{"cluster": "eu-1", "id": "sidecar"}
+
+	testCases := map[LabelCollisionPolicy]string{
+		CollisionRename:    `syslogng_output_events_total{cluster="eu-1",exported_exported_id="d_dest",exported_id="x",id="sidecar",result="delivered"} 3`,
+		CollisionOverwrite: `syslogng_output_events_total{cluster="eu-1",exported_id="x",id="sidecar",result="delivered"} 3`,
+		CollisionKeep:      `syslogng_output_events_total{cluster="eu-1",exported_id="x",id="d_dest",result="delivered"} 3`,
+	}
+	for policy, expected := range testCases {
+		t.Run(string(policy), func(t *testing.T) {
+			e := New(ctl, WithLogger(slog.New(slog.DiscardHandler)), WithExternalLabels(labels, policy), WithGatherer(extra))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			body := rec.Body.String()
+			assert.Contains(t, body, expected+"\n")
+			assert.Contains(t, body, `syslogng_up{cluster="eu-1",id="sidecar"} 1`+"\n")
+			assert.Contains(t, body, `extra_gauge{cluster="eu-1",id="sidecar"} 0`+"\n")
+		})
+	}
+}
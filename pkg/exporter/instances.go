@@ -0,0 +1,156 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+// DefaultInstanceLabel is the label holding the name of the syslog-ng instance of the series of a multi-instance exporter
+const DefaultInstanceLabel = "syslogng"
+
+// Instance is a syslog-ng instance queried by a multi-instance exporter, e.g. a relay and a collector on the same host
+type Instance struct {
+	// Name is the value of the instance label of the series of the instance
+	Name       string
+	Controller *syslogngctl.Controller
+	// Timeout is the timeout of querying the instance (default: the timeout of the exporter, see WithTimeout)
+	Timeout time.Duration
+}
+
+// InstanceError is the error of querying an instance of a multi-instance exporter
+type InstanceError struct {
+	Instance string
+	Err      error
+}
+
+func (e *InstanceError) Error() string {
+	return fmt.Sprintf("syslog-ng instance %q: %v", e.Instance, e.Err)
+}
+
+func (e *InstanceError) Unwrap() error {
+	return e.Err
+}
+
+// NewMultiInstance returns an exporter querying the instances concurrently. Every series, including syslogng_up and
+// the other metrics describing the queries, gets the label with the name of its instance, so an instance being
+// down does not fail the others. The transformers see the labeled series of every instance.
+func NewMultiInstance(label string, instances []Instance, opts ...Option) (*Exporter, error) {
+	if label == "" {
+		label = DefaultInstanceLabel
+	}
+	if err := ValidateExternalLabelName(label); err != nil {
+		return nil, fmt.Errorf("invalid instance label: %w", err)
+	}
+	if len(instances) == 0 {
+		return nil, errors.New("no syslog-ng instances")
+	}
+	names := make(map[string]bool, len(instances))
+	insts := make([]*instance, 0, len(instances))
+	for _, inst := range instances {
+		switch {
+		case inst.Name == "":
+			return nil, errors.New("syslog-ng instance without name")
+		case names[inst.Name]:
+			return nil, fmt.Errorf("duplicate syslog-ng instance %q", inst.Name)
+		case inst.Controller == nil:
+			return nil, fmt.Errorf("syslog-ng instance %q without controller", inst.Name)
+		}
+		names[inst.Name] = true
+		insts = append(insts, &instance{
+			Instance: inst,
+			labels: &externalLabels{
+				labels:      []*io_prometheus_client.LabelPair{{Name: new(label), Value: new(inst.Name)}},
+				onCollision: CollisionRename,
+			},
+		})
+	}
+	return newExporter(insts, opts...), nil
+}
+
+// instance is a queried syslog-ng instance, the labels are nil if the exporter has a single unnamed instance
+type instance struct {
+	Instance
+	labels *externalLabels
+	status scrapeStatus
+}
+
+// instanceResult is the outcome of querying an instance
+type instanceResult struct {
+	mfs      []*io_prometheus_client.MetricFamily
+	err      error
+	duration time.Duration
+}
+
+// query queries the metrics of the instance for the scraper and attaches the instance label
+func (inst *instance) query(ctx context.Context, scraper string) instanceResult {
+	subCtx, cancel := context.WithTimeout(ctx, inst.Timeout)
+	defer cancel()
+	start := time.Now()
+	var mfs []*io_prometheus_client.MetricFamily
+	var err error
+	if scraper == sharedScraper {
+		mfs, err = inst.Controller.StatsPrometheusAllSamples(subCtx)
+	} else {
+		mfs, err = inst.Controller.StatsPrometheusForScraper(subCtx, scraper)
+	}
+	if err != nil && inst.labels != nil {
+		err = &InstanceError{Instance: inst.Name, Err: err}
+	}
+	return instanceResult{mfs: inst.labels.apply(mfs), err: err, duration: time.Since(start)}
+}
+
+// queryFailed tells whether the error returned by a query means that none of the instances returned its metrics.
+// Partial responses are not failures.
+func (e *Exporter) queryFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+	// the errors of several instances are joined, the error of a single instance may be a joined error too, e.g. the
+	// malformed lines of a response
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok && len(e.instances) > 1 {
+		errs = joined.Unwrap()
+	}
+	failed := 0
+	for _, err := range errs {
+		if !errors.As(err, new(*syslogngctl.PartialResponseError)) {
+			failed++
+		}
+	}
+	return failed == len(e.instances)
+}
+
+// mergeFamilies appends the series of the families of src to the families of dst with the same name, and the
+// other families to dst. The families of dst are modified, so they must be owned by the caller.
+func mergeFamilies(dst, src []*io_prometheus_client.MetricFamily) []*io_prometheus_client.MetricFamily {
+	for _, mf := range src {
+		i := slices.IndexFunc(dst, func(d *io_prometheus_client.MetricFamily) bool { return d.GetName() == mf.GetName() })
+		if i < 0 {
+			dst = append(dst, mf)
+			continue
+		}
+		dst[i].Metric = append(dst[i].Metric, mf.Metric...)
+	}
+	return dst
+}
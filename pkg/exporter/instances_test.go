@@ -0,0 +1,141 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+func TestMultiInstance(t *testing.T) {
+	relay := syslogngctl.NewController(controlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		return statsPrometheusOutput, nil
+	}))
+	var collectorDown atomic.Bool
+	collector := syslogngctl.NewController(controlChannelFunc(func(ctx context.Context, _ string) (string, error) {
+		if collectorDown.Load() {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		return statsPrometheusOutput, nil
+	}))
+	var transformed []string
+	record := TransformerFunc(func(mfs []*io_prometheus_client.MetricFamily) []*io_prometheus_client.MetricFamily {
+		transformed = nil
+		for _, mf := range mfs {
+			for _, m := range mf.GetMetric() {
+				transformed = append(transformed, mf.GetName()+" "+labelValue(m, "syslogng"))
+			}
+		}
+		return mfs
+	})
+
+	e, err := NewMultiInstance("", []Instance{
+		{Name: "relay", Controller: relay},
+		{Name: "collector", Controller: collector, Timeout: 10 * time.Millisecond},
+	}, WithLogger(slog.New(slog.DiscardHandler)), WithTimeout(time.Minute), WithTransformers(record), WithBadGatewayOnError(true))
+	require.NoError(t, err)
+
+	scrape := func() (int, string) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		body, _ := io.ReadAll(rec.Body)
+		return rec.Code, string(body)
+	}
+
+	code, body := scrape()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `syslogng_output_events_total{id="d_dest",result="delivered",syslogng="relay"} 3`)
+	assert.Contains(t, body, `syslogng_output_events_total{id="d_dest",result="delivered",syslogng="collector"} 3`)
+	assert.Contains(t, body, `syslogng_up{syslogng="relay"} 1`)
+	assert.Contains(t, body, `syslogng_up{syslogng="collector"} 1`)
+	assert.ElementsMatch(t, []string{
+		"syslogng_scratch_buffers_count relay", "syslogng_output_events_total relay",
+		"syslogng_scratch_buffers_count collector", "syslogng_output_events_total collector",
+	}, transformed, "the transformers see the labeled series of every instance")
+
+	collectorDown.Store(true)
+	start := time.Now()
+	mfs, err := e.Gather(context.Background(), "a")
+	assert.Less(t, time.Since(start), time.Minute/2, "the timeout of the instance applies")
+	var instErr *InstanceError
+	require.ErrorAs(t, err, &instErr)
+	assert.Equal(t, "collector", instErr.Instance)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, map[string]float64{"relay": 1, "collector": 0}, upByInstance(mfs))
+
+	code, body = scrape()
+	assert.Equal(t, http.StatusOK, code, "one instance being down does not fail the others")
+	assert.Contains(t, body, `syslogng_output_events_total{id="d_dest",result="delivered",syslogng="relay"} 3`)
+	assert.NotContains(t, body, `syslogng_output_events_total{id="d_dest",result="delivered",syslogng="collector"}`)
+	assert.Contains(t, body, `syslogng_scrape_error{class="timeout",syslogng="collector"} 1`)
+
+	assert.True(t, e.queryFailed(errors.Join(&InstanceError{"relay", errors.New("a")}, &InstanceError{"collector", errors.New("b")})))
+	assert.False(t, e.queryFailed(&InstanceError{"collector", errors.New("b")}))
+}
+
+func TestNewMultiInstanceInvalid(t *testing.T) {
+	ctl := syslogngctl.NewController(controlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		return statsPrometheusOutput, nil
+	}))
+	for name, tt := range map[string]struct {
+		label     string
+		instances []Instance
+	}{
+		"no instances":   {},
+		"invalid label":  {label: "not-a-label", instances: []Instance{{Name: "a", Controller: ctl}}},
+		"reserved label": {label: "__name__", instances: []Instance{{Name: "a", Controller: ctl}}},
+		"no name":        {instances: []Instance{{Controller: ctl}}},
+		"duplicate name": {instances: []Instance{{Name: "a", Controller: ctl}, {Name: "a", Controller: ctl}}},
+		"no controller":  {instances: []Instance{{Name: "a"}}},
+	} {
+		_, err := NewMultiInstance(tt.label, tt.instances)
+		assert.Error(t, err, name)
+	}
+}
+
+func upByInstance(mfs []*io_prometheus_client.MetricFamily) map[string]float64 {
+	res := make(map[string]float64)
+	for _, mf := range mfs {
+		if mf.GetName() == "syslogng_up" {
+			for _, m := range mf.GetMetric() {
+				res[labelValue(m, "syslogng")] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	return res
+}
+
+func labelValue(m *io_prometheus_client.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+// Instrumentation instruments the exporter itself: the control socket commands, the processing of the responses,
+// the HTTP handlers and the size of the scrapes.
+//
+// Use InstrumentControlChannel and ObserveStats (see syslogngctl.WithStatsTrace) when creating the Controller,
+// and pass the Instrumentation to the Exporter with WithInstrumentation.
+type Instrumentation struct {
+	registry *prometheus.Registry
+
+	commandDuration   *prometheus.HistogramVec
+	commandErrors     *prometheus.CounterVec
+	responseSize      *prometheus.HistogramVec
+	parseDuration     prometheus.Histogram
+	transformDuration prometheus.Histogram
+	httpRequests      *prometheus.CounterVec
+	scrapeFamilies    prometheus.Gauge
+	scrapeSeries      prometheus.Gauge
+}
+
+func NewInstrumentation() *Instrumentation {
+	m := &Instrumentation{
+		registry: prometheus.NewRegistry(),
+		commandDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "syslogng_exporter_command_duration_seconds",
+			Help:    "Round-trip time of the commands sent to the syslog-ng control socket.",
+			Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"command"}),
+		commandErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "syslogng_exporter_command_errors_total",
+			Help: "Number of commands sent to the syslog-ng control socket that failed.",
+		}, []string{"command"}),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "syslogng_exporter_response_size_bytes",
+			Help:    "Size of the responses received on the syslog-ng control socket.",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 8),
+		}, []string{"command"}),
+		parseDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "syslogng_exporter_parse_duration_seconds",
+			Help:    "Time it took to parse the metrics returned by syslog-ng.",
+			Buckets: prometheus.ExponentialBuckets(.0001, 4, 8),
+		}),
+		transformDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "syslogng_exporter_transform_duration_seconds",
+			Help:    "Time it took to type, annotate and deduplicate the metrics returned by syslog-ng.",
+			Buckets: prometheus.ExponentialBuckets(.0001, 4, 8),
+		}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "syslogng_exporter_http_requests_total",
+			Help: "Number of HTTP requests served by the exporter by handler and status code.",
+		}, []string{"handler", "code"}),
+		scrapeFamilies: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "syslogng_exporter_scrape_metric_families",
+			Help: "Number of metric families returned by syslog-ng in the last scrape.",
+		}),
+		scrapeSeries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "syslogng_exporter_scrape_series",
+			Help: "Number of series returned by syslog-ng in the last scrape.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.commandDuration,
+		m.commandErrors,
+		m.responseSize,
+		m.parseDuration,
+		m.transformDuration,
+		m.httpRequests,
+		m.scrapeFamilies,
+		m.scrapeSeries,
+	)
+	return m
+}
+
+// InstrumentControlChannel measures the round-trip time and response size of every command sent on cc
+func (m *Instrumentation) InstrumentControlChannel(cc syslogngctl.ControlChannel) syslogngctl.ControlChannel {
+	return instrumentedControlChannel{ControlChannel: cc, metrics: m}
+}
+
+// ObserveStats records the processing times of a StatsPrometheus query, see syslogngctl.WithStatsTrace
+func (m *Instrumentation) ObserveStats(trace syslogngctl.StatsTrace) {
+	m.parseDuration.Observe(trace.Parse.Seconds())
+	m.transformDuration.Observe(trace.Transform.Seconds())
+}
+
+// observeScrape records the size of the metrics returned by syslog-ng
+func (m *Instrumentation) observeScrape(mfs []*io_prometheus_client.MetricFamily) {
+	series := 0
+	for _, mf := range mfs {
+		series += len(mf.Metric)
+	}
+	m.scrapeFamilies.Set(float64(len(mfs)))
+	m.scrapeSeries.Set(float64(series))
+}
+
+// InstrumentHandler counts the requests served by the handler by status code
+func (m *Instrumentation) InstrumentHandler(name string, handler http.Handler) http.Handler {
+	return promhttp.InstrumentHandlerCounter(m.httpRequests.MustCurryWith(prometheus.Labels{"handler": name}), handler)
+}
+
+// Registry returns the registry of the exporter metrics, including the Go runtime and process collectors
+func (m *Instrumentation) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics of the exporter
+func (m *Instrumentation) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+type instrumentedControlChannel struct {
+	syslogngctl.ControlChannel
+	metrics *Instrumentation
+}
+
+func (cc instrumentedControlChannel) SendCommand(ctx context.Context, cmd string) (string, error) {
+	command := commandName(cmd)
+	start := time.Now()
+	rsp, err := cc.ControlChannel.SendCommand(ctx, cmd)
+	cc.metrics.commandDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+	if err != nil {
+		cc.metrics.commandErrors.WithLabelValues(command).Inc()
+		return rsp, err
+	}
+	cc.metrics.responseSize.WithLabelValues(command).Observe(float64(len(rsp)))
+	return rsp, nil
+}
+
+// commandName returns the command without its arguments to keep the cardinality of the command label low
+func commandName(cmd string) string {
+	fields := strings.Fields(cmd)
+	for i, f := range fields {
+		if strings.ToUpper(f) != f || strings.ContainsAny(f, "=.\"'/") {
+			fields = fields[:i]
+			break
+		}
+	}
+	if len(fields) > 3 {
+		fields = fields[:3]
+	}
+	return strings.Join(fields, " ")
+}
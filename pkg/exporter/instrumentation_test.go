@@ -0,0 +1,71 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+type controlChannelFunc func(ctx context.Context, cmd string) (string, error)
+
+func (fn controlChannelFunc) SendCommand(ctx context.Context, cmd string) (string, error) {
+	return fn(ctx, cmd)
+}
+
+func TestCommandName(t *testing.T) {
+	assert.Equal(t, "STATS PROMETHEUS", commandName("STATS PROMETHEUS"))
+	assert.Equal(t, "STATS PROMETHEUS WITH_LEGACY", commandName("STATS PROMETHEUS WITH_LEGACY"))
+	assert.Equal(t, "LOG VERBOSE ON", commandName("LOG VERBOSE ON ignored"))
+	assert.Equal(t, "STATS", commandName("STATS foo=bar"))
+	assert.Equal(t, "CONFIG GET ORIGINAL", commandName("CONFIG GET ORIGINAL"))
+}
+
+func TestSelfMetrics(t *testing.T) {
+	m := NewInstrumentation()
+	ctl := syslogngctl.NewController(
+		m.InstrumentControlChannel(controlChannelFunc(func(_ context.Context, cmd string) (string, error) {
+			if cmd == "LICENSE" {
+				return "", errors.New("unreachable")
+			}
+			return "syslogng_scratch_buffers_count 2\nsyslogng_input_events_total{id=\"a\"} 1\nsyslogng_input_events_total{id=\"b\"} 1\n", nil
+		})),
+		syslogngctl.WithStatsTrace(m.ObserveStats),
+	)
+
+	mfs, err := ctl.StatsPrometheus(context.Background())
+	require.NoError(t, err)
+	m.observeScrape(mfs)
+	assert.Error(t, ctl.Ping(context.Background()))
+
+	assert.Equal(t, 2, testutil.CollectAndCount(m.commandDuration))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.commandErrors.WithLabelValues("LICENSE")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.responseSize))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.parseDuration))
+	assert.NoError(t, testutil.CollectAndCompare(m.scrapeSeries, strings.NewReader(`
+# HELP syslogng_exporter_scrape_series Number of series returned by syslog-ng in the last scrape.
+# TYPE syslogng_exporter_scrape_series gauge
+syslogng_exporter_scrape_series 3
+`)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.scrapeFamilies))
+}
@@ -0,0 +1,225 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+// probeTimeoutOffset is subtracted from the scrape timeout of Prometheus, so that the response arrives in time
+const probeTimeoutOffset = 500 * time.Millisecond
+
+// Prober serves the metrics of the syslog-ng control endpoint in the target query parameter along with the outcome
+// of the probe, like the blackbox_exporter. Targets are URLs: unix:///path/to/syslog-ng.ctl, or tcp://host:port and
+// tls://host:port for control sockets exposed over the network (e.g. by socat or stunnel). Every probe uses a new
+// Controller, so only the stateless transformers (e.g. relabel.Rules) make sense for probes.
+type Prober struct {
+	allowed      []*regexp.Regexp
+	timeout      time.Duration
+	tlsConfig    *tls.Config
+	ctlOpts      []syslogngctl.ControllerOption
+	transformers []Transformer
+	logger       *slog.Logger
+}
+
+// ProberOption is an option for NewProber
+type ProberOption func(*Prober)
+
+// WithProbeLogger sets the logger of the prober (default: slog.Default)
+func WithProbeLogger(logger *slog.Logger) ProberOption {
+	return func(p *Prober) {
+		p.logger = logger
+	}
+}
+
+// WithProbeTimeout sets the timeout of a probe (default: DefaultTimeout). The scrape timeout of Prometheus
+// shortens it if lower.
+func WithProbeTimeout(timeout time.Duration) ProberOption {
+	return func(p *Prober) {
+		p.timeout = timeout
+	}
+}
+
+// WithProbeTLSConfig sets the TLS configuration of the tls:// targets
+func WithProbeTLSConfig(cfg *tls.Config) ProberOption {
+	return func(p *Prober) {
+		p.tlsConfig = cfg
+	}
+}
+
+// WithProbeControllerOptions sets the options of the Controllers of the probes, e.g. the metric catalog
+func WithProbeControllerOptions(opts ...syslogngctl.ControllerOption) ProberOption {
+	return func(p *Prober) {
+		p.ctlOpts = append(p.ctlOpts, opts...)
+	}
+}
+
+// WithProbeTransformers appends transformers applied in order to the metric families of the targets
+func WithProbeTransformers(transformers ...Transformer) ProberOption {
+	return func(p *Prober) {
+		p.transformers = append(p.transformers, transformers...)
+	}
+}
+
+// NewProber returns a prober of the targets matching any of the allowed patterns, which are regular expressions
+// matching the whole target URL. At least one pattern is required, so that the exporter can not be used to connect
+// to arbitrary endpoints.
+func NewProber(allowed []string, opts ...ProberOption) (*Prober, error) {
+	if len(allowed) == 0 {
+		return nil, errors.New("no allowed probe targets")
+	}
+	p := &Prober{
+		timeout: DefaultTimeout,
+		logger:  slog.Default(),
+	}
+	for _, pattern := range allowed {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed probe target %q: %w", pattern, err)
+		}
+		p.allowed = append(p.allowed, re)
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+// controlChannel returns the control channel of the target, if it is a valid URL
+func (p *Prober) controlChannel(target string) (syslogngctl.ControlChannel, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target %q: %w", target, err)
+	}
+	// the allowed patterns match the target as is, so it must not be encoded
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" || strings.Contains(target, "%") {
+		return nil, fmt.Errorf("invalid target %q: user info, query, fragment and percent-encoding are not allowed", target)
+	}
+	switch u.Scheme {
+	case "unix":
+		// unclean paths could escape the allowed patterns, e.g. /var/run/syslog-ng-.*/syslog-ng.ctl
+		if u.Host != "" || u.Path == "" || !path.IsAbs(u.Path) || path.Clean(u.Path) != u.Path {
+			return nil, fmt.Errorf("invalid target %q: must be unix:///absolute/path", target)
+		}
+		return syslogngctl.NewUnixDomainSocketControlChannel(u.Path), nil
+	case "tcp", "tls":
+		if u.Port() == "" || u.Hostname() == "" || (u.Path != "" && u.Path != "/") {
+			return nil, fmt.Errorf("invalid target %q: must be %s://host:port", target, u.Scheme)
+		}
+		if u.Scheme == "tcp" {
+			return syslogngctl.NewTCPControlChannel(u.Host), nil
+		}
+		return syslogngctl.NewTLSControlChannel(u.Host, p.tlsConfig), nil
+	default:
+		return nil, fmt.Errorf("invalid target %q: scheme must be unix, tcp or tls", target)
+	}
+}
+
+// probeTimeout returns the timeout of the probe, shortened to the scrape timeout of Prometheus if lower
+func (p *Prober) probeTimeout(r *http.Request) time.Duration {
+	timeout := p.timeout
+	if seconds, err := strconv.ParseFloat(r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"), 64); err == nil && seconds > 0 {
+		scrapeTimeout := time.Duration(seconds * float64(time.Second))
+		if scrapeTimeout > 2*probeTimeoutOffset {
+			scrapeTimeout -= probeTimeoutOffset
+		}
+		timeout = min(timeout, scrapeTimeout)
+	}
+	return timeout
+}
+
+// probe queries the metrics of the target and adds probe_success, probe_duration_seconds and syslogng_scrape_error
+func (p *Prober) probe(ctx context.Context, target string, ch syslogngctl.ControlChannel, logger *slog.Logger) []*io_prometheus_client.MetricFamily {
+	start := time.Now()
+	mfs, err := syslogngctl.NewController(ch, p.ctlOpts...).StatsPrometheus(ctx)
+	duration := time.Since(start)
+	class := classifyScrapeError(err)
+	if err != nil {
+		logger.Warn("probing syslog-ng failed", "target", target, "error", err, "class", class, "metricFamilies", len(mfs))
+	}
+	for _, t := range p.transformers {
+		mfs = t.Transform(mfs)
+	}
+
+	success := 0.0
+	if class == "" || class == scrapeErrorParse {
+		success = 1
+	}
+	return append(mfs,
+		gaugeFamily("probe_success", "Whether the syslog-ng control endpoint returned its metrics (1) or not (0).", "", success),
+		gaugeFamily("probe_duration_seconds", "Time it took to probe the syslog-ng control endpoint.", "seconds", duration.Seconds()),
+		scrapeErrorFamily(class),
+	)
+}
+
+// ServeHTTP probes the target in the query and serves its metrics in the exposition format negotiated with the
+// client. Failed probes are reported by probe_success, invalid and forbidden targets are rejected.
+func (p *Prober) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := p.logger.With("remote", r.RemoteAddr, "userAgent", r.UserAgent(), "path", r.URL.Path)
+
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return
+	}
+	if !slices.ContainsFunc(p.allowed, func(re *regexp.Regexp) bool { return re.MatchString(target) }) {
+		http.Error(w, fmt.Sprintf("target %q is not allowed", target), http.StatusForbidden)
+		logger.Warn("probe target not allowed", "target", target)
+		return
+	}
+	ch, err := p.controlChannel(target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), p.probeTimeout(r))
+	defer cancel()
+	mfs := p.probe(ctx, target, ch, logger)
+
+	var resp bytes.Buffer
+	format := negotiateFormat(r.Header)
+	if err := encodeMetricFamilies(&resp, format, mfs); err != nil {
+		http.Error(w, "failed to convert metrics", http.StatusInternalServerError)
+		logger.Error("metrics conversion failed", "target", target, "error", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", string(format))
+	bodyLen, err := io.Copy(w, &resp)
+	if err != nil {
+		logger.Error("writing response failed", "target", target, "error", err)
+		return
+	}
+	logger.Info("writing probe response", "target", target, "bodyLength", bodyLen, "format", format)
+}
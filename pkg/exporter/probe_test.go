@@ -0,0 +1,142 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveControlSocket answers every command on the listener with the response of STATS PROMETHEUS
+func serveControlSocket(t *testing.T, l net.Listener) {
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if _, err := bufio.NewReader(conn).ReadString('\n'); err == nil {
+					io.WriteString(conn, statsPrometheusOutput+".\n")
+				}
+			}()
+		}
+	}()
+}
+
+func TestProber(t *testing.T) {
+	dir, err := os.MkdirTemp("", "probe")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socketPath := filepath.Join(dir, "syslog-ng.ctl")
+	unixListener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	serveControlSocket(t, unixListener)
+
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serveControlSocket(t, tcpListener)
+
+	// the certificate of httptest is valid for 127.0.0.1
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+	tlsListener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: ts.TLS.Certificates})
+	require.NoError(t, err)
+	serveControlSocket(t, tlsListener)
+
+	p, err := NewProber([]string{"unix://" + dir + "/.*", `tcp://127\.0\.0\.1:\d+`, `tls://127\.0\.0\.1:\d+`},
+		WithProbeLogger(slog.New(slog.DiscardHandler)),
+		WithProbeTimeout(time.Second),
+		WithProbeTLSConfig(ts.Client().Transport.(*http.Transport).TLSClientConfig),
+	)
+	require.NoError(t, err)
+
+	probe := func(target string) (int, string) {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe?"+url.Values{"target": {target}}.Encode(), nil))
+		body, _ := io.ReadAll(rec.Body)
+		return rec.Code, string(body)
+	}
+
+	for _, target := range []string{
+		"unix://" + socketPath,
+		"tcp://" + tcpListener.Addr().String(),
+		"tls://" + tlsListener.Addr().String(),
+	} {
+		code, body := probe(target)
+		assert.Equal(t, http.StatusOK, code, target)
+		assert.Contains(t, body, `syslogng_output_events_total{id="d_dest",result="delivered"} 3`, target)
+		assert.Contains(t, body, "probe_success 1\n", target)
+		assert.Contains(t, body, "probe_duration_seconds ", target)
+	}
+
+	code, body := probe("unix://" + dir + "/missing.ctl")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "probe_success 0\n")
+	assert.Contains(t, body, `syslogng_scrape_error{class="connection"} 1`)
+
+	for target, expected := range map[string]int{
+		"":                                   http.StatusBadRequest,
+		"unix:///var/run/syslog-ng.ctl":      http.StatusForbidden,
+		"tcp://192.0.2.1:1234":               http.StatusForbidden,
+		"unix://" + dir + "/../etc/x.ctl":    http.StatusBadRequest,
+		"unix://" + dir + "/%2e%2e/x.ctl":    http.StatusBadRequest,
+		"unix://" + dir + "/x.ctl?a=b":       http.StatusBadRequest,
+		"tcp://127.0.0.1:1234/path":          http.StatusForbidden,
+		"udp://127.0.0.1:1234":               http.StatusForbidden,
+		"tcp://user@127.0.0.1:1234":          http.StatusForbidden,
+		"tls://127.0.0.1:" + "1234#fragment": http.StatusForbidden,
+	} {
+		code, _ := probe(target)
+		assert.Equal(t, expected, code, target)
+	}
+
+	for _, target := range []string{"tcp://127.0.0.1:1234/path", "tls://127.0.0.1", "unix://host/x.ctl", "unix:x.ctl"} {
+		_, err := p.controlChannel(target)
+		assert.Error(t, err, target)
+	}
+
+	_, err = NewProber(nil)
+	assert.Error(t, err)
+	_, err = NewProber([]string{"("})
+	assert.Error(t, err)
+}
+
+func TestProberTimeout(t *testing.T) {
+	p, err := NewProber([]string{".*"}, WithProbeTimeout(10*time.Second))
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/probe", nil)
+	assert.Equal(t, 10*time.Second, p.probeTimeout(r))
+	r.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "5")
+	assert.Equal(t, 4500*time.Millisecond, p.probeTimeout(r))
+	r.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "0.5")
+	assert.Equal(t, 500*time.Millisecond, p.probeTimeout(r))
+}
@@ -0,0 +1,138 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+// Scrape error classes reported by syslogng_scrape_error
+const (
+	scrapeErrorTimeout    = "timeout"
+	scrapeErrorConnection = "connection"
+	scrapeErrorCommand    = "command"
+	scrapeErrorResponse   = "response"
+	scrapeErrorParse      = "parse"
+	scrapeErrorOther      = "other"
+)
+
+var scrapeErrorClasses = []string{scrapeErrorTimeout, scrapeErrorConnection, scrapeErrorCommand, scrapeErrorResponse, scrapeErrorParse, scrapeErrorOther}
+
+// classifyScrapeError returns the class of an error returned by StatsPrometheus, or an empty string if err is nil
+func classifyScrapeError(err error) string {
+	var (
+		commandFailure  syslogngctl.CommandFailure
+		missingTerm     syslogngctl.MissingResponseTerminator
+		parseError      expfmt.ParseError
+		invalidStatLine syslogngctl.InvalidStatLine
+		typeMismatch    syslogngctl.MetricTypeMismatch
+		netError        net.Error
+		numError        *strconv.NumError
+	)
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return scrapeErrorTimeout
+	case errors.As(err, &commandFailure):
+		return scrapeErrorCommand
+	case errors.As(err, &missingTerm):
+		return scrapeErrorResponse
+	case errors.As(err, &parseError), errors.As(err, &invalidStatLine), errors.As(err, &typeMismatch), errors.As(err, &numError):
+		return scrapeErrorParse
+	case errors.As(err, &netError), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return scrapeErrorConnection
+	default:
+		return scrapeErrorOther
+	}
+}
+
+// scrapeStatus reports the outcome of querying syslog-ng, so that an unreachable syslog-ng can be told apart
+// from an unreachable exporter
+type scrapeStatus struct {
+	lastSuccess atomic.Int64 // Unix time in milliseconds
+}
+
+// metricFamilies returns the syslogng_up, syslogng_scrape_duration_seconds, syslogng_scrape_error and
+// syslogng_last_successful_scrape_timestamp_seconds metrics of a scrape.
+//
+// syslog-ng is considered up if it returned its metrics, even if some of them could not be parsed.
+func (s *scrapeStatus) metricFamilies(err error, duration time.Duration, now time.Time) []*io_prometheus_client.MetricFamily {
+	class := classifyScrapeError(err)
+	if err == nil {
+		s.lastSuccess.Store(now.UnixMilli())
+	}
+
+	up := 0.0
+	if class == "" || class == scrapeErrorParse {
+		up = 1
+	}
+
+	mfs := []*io_prometheus_client.MetricFamily{
+		gaugeFamily("syslogng_up", "Whether syslog-ng returned its metrics (1) or not (0).", "", up),
+		gaugeFamily("syslogng_scrape_duration_seconds", "Time it took to query the metrics of syslog-ng.", "seconds", duration.Seconds()),
+		scrapeErrorFamily(class),
+	}
+	if lastSuccess := s.lastSuccess.Load(); lastSuccess != 0 {
+		mfs = append(mfs, gaugeFamily("syslogng_last_successful_scrape_timestamp_seconds", "Time of the last scrape without errors, in Unix time.", "seconds", float64(lastSuccess)/1000))
+	}
+	return mfs
+}
+
+// scrapeErrorFamily returns the syslogng_scrape_error metric, which is 1 for the class of the error and 0 for the others
+func scrapeErrorFamily(class string) *io_prometheus_client.MetricFamily {
+	mf := &io_prometheus_client.MetricFamily{
+		Name: new("syslogng_scrape_error"),
+		Help: new("Whether querying syslog-ng failed with the given class of error (1) or not (0)."),
+		Type: io_prometheus_client.MetricType_GAUGE.Enum(),
+	}
+	for _, c := range scrapeErrorClasses {
+		value := 0.0
+		if c == class {
+			value = 1
+		}
+		mf.Metric = append(mf.Metric, &io_prometheus_client.Metric{
+			Label: []*io_prometheus_client.LabelPair{{Name: new("class"), Value: new(c)}},
+			Gauge: &io_prometheus_client.Gauge{Value: new(value)},
+		})
+	}
+	return mf
+}
+
+func gaugeFamily(name string, help string, unit string, value float64) *io_prometheus_client.MetricFamily {
+	mf := &io_prometheus_client.MetricFamily{
+		Name: new(name),
+		Help: new(help),
+		Type: io_prometheus_client.MetricType_GAUGE.Enum(),
+		Metric: []*io_prometheus_client.Metric{
+			{Gauge: &io_prometheus_client.Gauge{Value: new(value)}},
+		},
+	}
+	if unit != "" {
+		mf.Unit = new(unit)
+	}
+	return mf
+}
@@ -0,0 +1,50 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+func TestClassifyScrapeError(t *testing.T) {
+	testCases := map[string]struct {
+		err      error
+		expected string
+	}{
+		"no error":          {err: nil, expected: ""},
+		"timeout":           {err: fmt.Errorf("query: %w", context.DeadlineExceeded), expected: scrapeErrorTimeout},
+		"missing socket":    {err: &net.OpError{Op: "dial", Net: "unix", Err: errors.New("connect: no such file or directory")}, expected: scrapeErrorConnection},
+		"command failure":   {err: syslogngctl.CommandFailure("Unknown command"), expected: scrapeErrorCommand},
+		"truncated":         {err: errors.Join(io.EOF, syslogngctl.MissingResponseTerminator{}), expected: scrapeErrorResponse},
+		"text format":       {err: expfmt.ParseError{Line: 3, Msg: "invalid metric name"}, expected: scrapeErrorParse},
+		"legacy stats":      {err: errors.Join(syslogngctl.InvalidStatLine("foo;bar")), expected: scrapeErrorParse},
+		"something unknown": {err: errors.New("unknown"), expected: scrapeErrorOther},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, classifyScrapeError(testCase.err))
+		})
+	}
+}
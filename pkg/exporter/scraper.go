@@ -0,0 +1,82 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const DefaultScraperIdentity = "remote-addr"
+
+// ScraperIdentifier tells scrapers apart, so that each of them receives every event delay sample
+type ScraperIdentifier func(r *http.Request) string
+
+// ParseScraperIdentity parses the scraper identity source, which is one of
+//   - remote-addr: the IP address of the client
+//   - header:<name>: the value of the named request header
+//   - query:<name>: the value of the named query parameter
+//
+// If the header or query parameter is missing, the IP address of the client is used.
+func ParseScraperIdentity(source string) (ScraperIdentifier, error) {
+	kind, name, _ := strings.Cut(source, ":")
+	switch {
+	case kind == "remote-addr" && name == "":
+		return remoteHost, nil
+	case kind == "header" && name != "":
+		return func(r *http.Request) string {
+			if id := r.Header.Get(name); id != "" {
+				return "header:" + id
+			}
+			return remoteHost(r)
+		}, nil
+	case kind == "query" && name != "":
+		return func(r *http.Request) string {
+			if id := r.URL.Query().Get(name); id != "" {
+				return "query:" + id
+			}
+			return remoteHost(r)
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid scraper identity %q, use remote-addr, header:<name> or query:<name>", source)
+	}
+}
+
+// selectionScraper returns the identity of the scraper of the series selected by the module and the selectors of the
+// query. Jobs scraping different series from the same host are different scrapers, so that each of them receives
+// every event delay sample.
+func selectionScraper(scraper string, query url.Values) string {
+	params := make(url.Values)
+	for _, name := range []string{"module", "match[]", "name[]", "label"} {
+		if query.Has(name) {
+			params[name] = query[name]
+		}
+	}
+	if len(params) == 0 {
+		return scraper
+	}
+	return scraper + "?" + params.Encode()
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,124 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/derived"
+)
+
+// Selection selects the series of syslog-ng served by the handler, after the transformers. The metrics describing
+// the query (e.g. syslogng_up) and the metrics of the exporter are always served. A nil selection selects every series.
+type Selection struct {
+	// match selects the series matching any of the selectors, every series if empty
+	match []*derived.Selector
+	// exclude drops the series matching any of the selectors
+	exclude []*derived.Selector
+	// labels selects the series matching every label filter
+	labels *derived.Selector
+}
+
+// NewSelection returns the selection of the series matching any of the match selectors (every series if none) and
+// none of the exclude selectors. Selectors are PromQL series selectors, e.g.
+// syslogng_output_events_total{result="dropped"} or {__name__=~"syslogng_output_.*"}.
+func NewSelection(match, exclude []string) (*Selection, error) {
+	s := &Selection{labels: derived.NewSelector("")}
+	for _, m := range match {
+		sel, err := derived.ParseSelector(m)
+		if err != nil {
+			return nil, err
+		}
+		s.match = append(s.match, sel)
+	}
+	for _, m := range exclude {
+		sel, err := derived.ParseSelector(m)
+		if err != nil {
+			return nil, err
+		}
+		s.exclude = append(s.exclude, sel)
+	}
+	return s, nil
+}
+
+// ParseSelection parses the selection from the query parameters of the handler: match[] (series selectors) and
+// name[] (metric names), any of them must match, and label (see ParseMetricsFilter), all of them must match.
+// It returns nil if none of them are set.
+func ParseSelection(query url.Values) (*Selection, error) {
+	if !query.Has("match[]") && !query.Has("name[]") && !query.Has("label") {
+		return nil, nil
+	}
+	s, err := NewSelection(query["match[]"], nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range query["name[]"] {
+		if !model.IsValidLegacyMetricName(name) {
+			return nil, fmt.Errorf("invalid metric name %q", name)
+		}
+		s.match = append(s.match, derived.NewSelector(name))
+	}
+	if s.labels, err = parseLabelMatchers(query["label"]); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Apply returns the selected series of the metric families, leaving out the families without selected series.
+// The metric families are not modified.
+func (s *Selection) Apply(mfs []*io_prometheus_client.MetricFamily) []*io_prometheus_client.MetricFamily {
+	if s == nil {
+		return mfs
+	}
+	res := make([]*io_prometheus_client.MetricFamily, 0, len(mfs))
+	for _, mf := range mfs {
+		var metrics []*io_prometheus_client.Metric
+		for _, m := range mf.GetMetric() {
+			if s.selects(mf.GetName(), m.GetLabel()) {
+				metrics = append(metrics, m)
+			}
+		}
+		switch len(metrics) {
+		case 0:
+		case len(mf.GetMetric()):
+			res = append(res, mf)
+		default:
+			res = append(res, &io_prometheus_client.MetricFamily{
+				Name:   mf.Name,
+				Help:   mf.Help,
+				Type:   mf.Type,
+				Unit:   mf.Unit,
+				Metric: metrics,
+			})
+		}
+	}
+	return res
+}
+
+func (s *Selection) selects(name string, labels []*io_prometheus_client.LabelPair) bool {
+	matches := func(sel *derived.Selector) bool { return sel.Matches(name, labels) }
+	if len(s.match) > 0 && !slices.ContainsFunc(s.match, matches) {
+		return false
+	}
+	if slices.ContainsFunc(s.exclude, matches) {
+		return false
+	}
+	return s.labels.Matches(name, labels)
+}
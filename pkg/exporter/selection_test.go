@@ -0,0 +1,176 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+func TestSelection(t *testing.T) {
+	mfs := []*io_prometheus_client.MetricFamily{
+		{
+			Name: new("syslogng_output_events_total"),
+			Type: io_prometheus_client.MetricType_COUNTER.Enum(),
+			Metric: []*io_prometheus_client.Metric{
+				{Label: labelPairs("id", "d1", "result", "delivered"), Counter: &io_prometheus_client.Counter{Value: new(10.0)}},
+				{Label: labelPairs("id", "d1", "result", "dropped"), Counter: &io_prometheus_client.Counter{Value: new(1.0)}},
+				{Label: labelPairs("id", "d2", "result", "delivered"), Counter: &io_prometheus_client.Counter{Value: new(20.0)}},
+			},
+		},
+		{
+			Name:   new("syslogng_input_events_total"),
+			Type:   io_prometheus_client.MetricType_COUNTER.Enum(),
+			Metric: []*io_prometheus_client.Metric{{Label: labelPairs("id", "s1"), Counter: &io_prometheus_client.Counter{Value: new(30.0)}}},
+		},
+		{
+			Name:   new("syslogng_scratch_buffers_count"),
+			Type:   io_prometheus_client.MetricType_GAUGE.Enum(),
+			Metric: []*io_prometheus_client.Metric{{Gauge: &io_prometheus_client.Gauge{Value: new(2.0)}}},
+		},
+	}
+	series := func(mfs []*io_prometheus_client.MetricFamily) []string {
+		var res []string
+		for _, mf := range mfs {
+			for _, m := range mf.GetMetric() {
+				s := mf.GetName()
+				for _, l := range m.GetLabel() {
+					s += " " + l.GetName() + "=" + l.GetValue()
+				}
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+
+	tests := []struct {
+		name     string
+		match    []string
+		exclude  []string
+		expected []string
+	}{
+		{
+			name:     "everything",
+			expected: series(mfs),
+		},
+		{
+			name:  "name and labels",
+			match: []string{`syslogng_output_events_total{result="delivered", id!~"d1"}`, "syslogng_scratch_buffers_count"},
+			expected: []string{
+				"syslogng_output_events_total id=d2 result=delivered",
+				"syslogng_scratch_buffers_count",
+			},
+		},
+		{
+			name:    "name regex and exclude",
+			match:   []string{`{__name__=~"syslogng_.*_events_total"}`},
+			exclude: []string{"{result=`dropped`}", `{id="d2"}`},
+			expected: []string{
+				"syslogng_output_events_total id=d1 result=delivered",
+				"syslogng_input_events_total id=s1",
+			},
+		},
+		{
+			name:     "missing labels are empty",
+			match:    []string{`{id=""}`},
+			expected: []string{"syslogng_scratch_buffers_count"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSelection(tt.match, tt.exclude)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, series(s.Apply(mfs)))
+		})
+	}
+	assert.Len(t, mfs[0].GetMetric(), 3, "the metric families are not modified")
+
+	s, err := ParseSelection(url.Values{"name[]": {"syslogng_output_events_total", "syslogng_input_events_total"}, "label": {"id=~d.*"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"syslogng_output_events_total id=d1 result=delivered",
+		"syslogng_output_events_total id=d1 result=dropped",
+		"syslogng_output_events_total id=d2 result=delivered",
+	}, series(s.Apply(mfs)))
+
+	s, err = ParseSelection(url.Values{"module": {"outputs"}})
+	require.NoError(t, err)
+	assert.Nil(t, s)
+
+	for _, selector := range []string{"", "{}", "1abc", `{id="a"`, `{id=a}`, `{id~"a"}`, `{id='a'}`, `{id="a"} x`, `{id=~"("}`, `a b`} {
+		_, err := NewSelection([]string{selector}, nil)
+		assert.Error(t, err, selector)
+	}
+	for _, query := range []url.Values{
+		{"match[]": {"{"}},
+		{"name[]": {"not-a-name"}},
+		{"label": {"id"}},
+	} {
+		_, err := ParseSelection(query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestExporterHandlerSelection(t *testing.T) {
+	ctl := syslogngctl.NewController(controlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		return statsPrometheusOutput, nil
+	}))
+	outputs, err := NewSelection([]string{`{__name__=~"syslogng_output_.*"}`}, nil)
+	require.NoError(t, err)
+	e := New(ctl, WithLogger(slog.New(slog.DiscardHandler)), WithModules(map[string]*Selection{"outputs-only": outputs}))
+
+	scrape := func(query string) (int, string) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics?"+query, nil))
+		body, _ := io.ReadAll(rec.Body)
+		return rec.Code, string(body)
+	}
+
+	code, body := scrape("module=outputs-only")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `syslogng_output_events_total{id="d_dest",result="delivered"} 3`)
+	assert.NotContains(t, body, "syslogng_scratch_buffers_count")
+	assert.Contains(t, body, "syslogng_up 1\n", "the metrics of the exporter are always served")
+
+	code, body = scrape(url.Values{"module": {"outputs-only"}, "match[]": {`{result="dropped"}`}}.Encode())
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotContains(t, body, "syslogng_output_events_total")
+	assert.Contains(t, body, "syslogng_up 1\n")
+
+	code, body = scrape("name[]=syslogng_scratch_buffers_count")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "syslogng_scratch_buffers_count 2\n")
+	assert.NotContains(t, body, "syslogng_output_events_total")
+
+	assert.Equal(t, "10.0.0.1", selectionScraper("10.0.0.1", url.Values{"target": {"a"}}))
+	assert.Equal(t, "10.0.0.1?match%5B%5D=%7Bid%3D%22d%22%7D&module=outputs-only",
+		selectionScraper("10.0.0.1", url.Values{"module": {"outputs-only"}, "match[]": {`{id="d"}`}, "target": {"a"}}))
+
+	code, _ = scrape("module=unknown")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = scrape("match[]=%7B")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,85 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+const skippedLogInterval = time.Minute
+
+// skippedItemsReporter counts the items skipped by the lenient parser, and logs them at most once per interval,
+// so that a persistently malformed line does not flood the log on every scrape
+type skippedItemsReporter struct {
+	interval time.Duration
+	created  time.Time
+	total    atomic.Uint64
+
+	mu         sync.Mutex
+	lastLog    time.Time
+	suppressed uint64
+}
+
+func newSkippedItemsReporter(interval time.Duration, created time.Time) *skippedItemsReporter {
+	return &skippedItemsReporter{
+		interval: interval,
+		created:  created,
+	}
+}
+
+// report counts the skipped items of a partial response and logs them unless an other report was logged recently
+func (r *skippedItemsReporter) report(logger *slog.Logger, partial *syslogngctl.PartialResponseError, now time.Time) {
+	r.total.Add(uint64(len(partial.Skipped)))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.lastLog.IsZero() && now.Sub(r.lastLog) < r.interval {
+		r.suppressed += uint64(len(partial.Skipped))
+		return
+	}
+
+	for _, item := range partial.Skipped {
+		logger.Warn("skipped malformed item of the syslog-ng response", "line", item.Line, "metricFamily", item.Family, "error", item.Err)
+	}
+	if r.suppressed > 0 {
+		logger.Warn("suppressed logging of skipped items", "count", r.suppressed, "interval", r.interval)
+	}
+	r.lastLog = now
+	r.suppressed = 0
+}
+
+func (r *skippedItemsReporter) metricFamily() *io_prometheus_client.MetricFamily {
+	return &io_prometheus_client.MetricFamily{
+		Name: new("syslogng_exporter_skipped_lines_total"),
+		Help: new("Number of malformed lines and metric families of the syslog-ng responses skipped by the lenient parser."),
+		Type: io_prometheus_client.MetricType_COUNTER.Enum(),
+		Metric: []*io_prometheus_client.Metric{
+			{
+				Counter: &io_prometheus_client.Counter{
+					Value:            new(float64(r.total.Load())),
+					CreatedTimestamp: timestamppb.New(r.created),
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+func TestSkippedItemsReporter(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	now := time.Now()
+	r := newSkippedItemsReporter(time.Minute, now)
+
+	partial := &syslogngctl.PartialResponseError{Skipped: []syslogngctl.SkippedItem{
+		{Line: 2, Family: "syslogng_a", Err: errors.New("bad value")},
+		{Line: 5, Err: errors.New("bad line")},
+	}}
+
+	r.report(logger, partial, now)
+	assert.Equal(t, 2, strings.Count(logs.String(), "skipped malformed item"))
+
+	logs.Reset()
+	r.report(logger, partial, now.Add(30*time.Second))
+	assert.Empty(t, logs.String())
+
+	r.report(logger, partial, now.Add(61*time.Second))
+	assert.Equal(t, 2, strings.Count(logs.String(), "skipped malformed item"))
+	assert.Contains(t, logs.String(), `msg="suppressed logging of skipped items" count=2`)
+
+	assert.Equal(t, 6.0, r.metricFamily().GetMetric()[0].GetCounter().GetValue())
+}
@@ -0,0 +1,164 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+)
+
+// sharedScraper is the scraper identity of the snapshots and the coalesced queries, which are shared by the
+// scrapers. They return the last event delay sample of every destination, which are filtered for each scraper by
+// delayWatermarks.
+const sharedScraper = "shared"
+
+// scrapeResult is the outcome of a query of syslog-ng, shared by the clients of a snapshot or a coalesced query.
+// The metric families must not be modified.
+type scrapeResult struct {
+	mfs      []*io_prometheus_client.MetricFamily
+	err      error
+	duration time.Duration
+	at       time.Time
+	// instances are the results of the instances in the order of Exporter.instances
+	instances []instanceResult
+}
+
+// snapshotCache holds the result of the last background poll
+type snapshotCache struct {
+	interval     time.Duration
+	maxStaleness time.Duration
+
+	mu   sync.RWMutex
+	last *scrapeResult
+}
+
+// get returns the last snapshot, unless it is older than the maximum staleness
+func (c *snapshotCache) get(now time.Time) (scrapeResult, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.last == nil || now.Sub(c.last.at) > c.maxStaleness {
+		return scrapeResult{}, false
+	}
+	return *c.last, true
+}
+
+func (c *snapshotCache) set(res scrapeResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.last = &res
+}
+
+// snapshot returns the last snapshot, or refreshes it if it is missing or too old
+func (e *Exporter) snapshot(logger *slog.Logger) scrapeResult {
+	if res, ok := e.snapshots.get(time.Now()); ok {
+		return res
+	}
+	return e.refreshSnapshot(logger)
+}
+
+// refreshSnapshot queries syslog-ng and stores the result as the snapshot, concurrent refreshes are coalesced
+func (e *Exporter) refreshSnapshot(logger *slog.Logger) scrapeResult {
+	res, _, _ := e.flights.Do("snapshot", func() (any, error) {
+		res := e.query(context.Background(), sharedScraper, logger)
+		e.snapshots.set(res)
+		return res, nil
+	})
+	return res.(scrapeResult)
+}
+
+// pollSnapshots refreshes the snapshot at every interval until ctx is done
+func (e *Exporter) pollSnapshots(ctx context.Context) {
+	ticker := time.NewTicker(e.snapshots.interval)
+	defer ticker.Stop()
+
+	for {
+		e.refreshSnapshot(e.logger)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// coalescedQuery queries syslog-ng, concurrent queries share the result
+func (e *Exporter) coalescedQuery(ctx context.Context, logger *slog.Logger) scrapeResult {
+	res, _, _ := e.flights.Do("coalesced", func() (any, error) {
+		// the query is shared, so it must not be canceled when the first client goes away
+		return e.query(context.WithoutCancel(ctx), sharedScraper, logger), nil
+	})
+	return res.(scrapeResult)
+}
+
+// delayWatermarks holds the time of the last shared query served to each scraper, so that every scraper receives
+// each event delay sample once
+type delayWatermarks struct {
+	// created is the watermark of new scrapers
+	created time.Time
+	ttl     time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newDelayWatermarks(created time.Time, ttl time.Duration) *delayWatermarks {
+	return &delayWatermarks{created: created, ttl: ttl, last: make(map[string]time.Time)}
+}
+
+// filter returns the metric families with the event delay samples taken after the previous query of the scraper,
+// and moves the watermark of the scraper to the time of the query. The metric families are not modified.
+func (w *delayWatermarks) filter(scraper string, mfs []*io_prometheus_client.MetricFamily, at time.Time) []*io_prometheus_client.MetricFamily {
+	w.mu.Lock()
+	now := time.Now()
+	for id, last := range w.last {
+		if now.Sub(last) > w.ttl {
+			delete(w.last, id)
+		}
+	}
+	watermark, ok := w.last[scraper]
+	if !ok {
+		watermark = w.created
+	}
+	if at.After(watermark) {
+		w.last[scraper] = at
+	}
+	w.mu.Unlock()
+
+	res := make([]*io_prometheus_client.MetricFamily, 0, len(mfs))
+	for _, mf := range mfs {
+		if mf.GetName() != delaySampleMetricName {
+			res = append(res, mf)
+			continue
+		}
+		filtered := &io_prometheus_client.MetricFamily{Name: mf.Name, Help: mf.Help, Type: mf.Type, Unit: mf.Unit}
+		for _, m := range mf.Metric {
+			if time.UnixMilli(m.GetTimestampMs()).After(watermark) {
+				filtered.Metric = append(filtered.Metric, m)
+			}
+		}
+		if len(filtered.Metric) > 0 {
+			res = append(res, filtered)
+		}
+	}
+	return res
+}
+
+func snapshotAgeMetric(age time.Duration) *io_prometheus_client.MetricFamily {
+	return gaugeFamily("syslogng_exporter_snapshot_age_seconds", "Time elapsed since the served metrics were queried from syslog-ng.", "seconds", age.Seconds())
+}
@@ -0,0 +1,145 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+func TestExporterCoalescesQueries(t *testing.T) {
+	var queries atomic.Int32
+	var sampleAge atomic.Int32
+	release := make(chan struct{})
+	ctl := syslogngctl.NewController(controlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		if queries.Add(1) == 1 {
+			<-release
+		}
+		return statsPrometheusOutput + fmt.Sprintf(`syslogng_output_event_delay_sample_seconds{id="d_dest"} 2
+syslogng_output_event_delay_sample_age_seconds{id="d_dest"} %d
+`, sampleAge.Load()), nil
+	}))
+	e := New(ctl, WithLogger(slog.New(slog.DiscardHandler)))
+
+	// the scrapes of different scrapers share the query, and each of them receives the delay sample
+	var wg sync.WaitGroup
+	for _, scraper := range []string{"a", "b", "c", "d", "e"} {
+		wg.Go(func() {
+			mfs, err := e.Gather(context.Background(), scraper)
+			assert.NoError(t, err)
+			assert.True(t, hasFamily(mfs, "syslogng_scratch_buffers_count"))
+			assert.True(t, hasFamily(mfs, delaySampleMetricName), scraper)
+		})
+	}
+	require.Eventually(t, func() bool { return queries.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond) // let the other scrapes join the query
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), queries.Load())
+
+	// the sample is not delivered again to the same scraper
+	sampleAge.Store(5)
+	mfs, err := e.Gather(context.Background(), "a")
+	require.NoError(t, err)
+	assert.False(t, hasFamily(mfs, delaySampleMetricName))
+	assert.Equal(t, int32(2), queries.Load())
+}
+
+func TestExporterSnapshots(t *testing.T) {
+	var queries atomic.Int32
+	ctl := syslogngctl.NewController(controlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		queries.Add(1)
+		return statsPrometheusOutput, nil
+	}))
+	e := New(ctl, WithLogger(slog.New(slog.DiscardHandler)), WithSnapshotPolling(time.Hour, 0))
+	assert.Equal(t, 2*time.Hour, e.snapshots.maxStaleness)
+
+	// the first scrape takes the snapshot on demand, the others are served from it
+	for _, scraper := range []string{"a", "b", "c"} {
+		mfs, err := e.Gather(context.Background(), scraper)
+		require.NoError(t, err)
+		assert.True(t, hasFamily(mfs, "syslogng_scratch_buffers_count"))
+		assert.True(t, hasFamily(mfs, "syslogng_exporter_snapshot_age_seconds"))
+	}
+	assert.Equal(t, int32(1), queries.Load())
+
+	// stale snapshots are refreshed
+	e.snapshots.last.at = time.Now().Add(-3 * time.Hour)
+	_, err := e.Gather(context.Background(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), queries.Load())
+
+	// Run polls the snapshots
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return queries.Load() == 3 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestExporterSnapshotsDelaySamples(t *testing.T) {
+	var sampleAge atomic.Int32
+	ctl := syslogngctl.NewController(controlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		return statsPrometheusOutput + fmt.Sprintf(`syslogng_output_event_delay_sample_seconds{id="d_dest"} 2
+syslogng_output_event_delay_sample_age_seconds{id="d_dest"} %d
+`, sampleAge.Load()), nil
+	}))
+	e := New(ctl, WithLogger(slog.New(slog.DiscardHandler)), WithSnapshotPolling(time.Hour, 0))
+	e.delayWatermarks.created = time.Now().Add(-time.Minute)
+	delivered := func(scraper string) bool {
+		mfs, err := e.Gather(context.Background(), scraper)
+		require.NoError(t, err)
+		return hasFamily(mfs, delaySampleMetricName)
+	}
+
+	// a scrapes every snapshot, b scrapes every other one
+	e.refreshSnapshot(e.logger)
+	assert.True(t, delivered("a"))
+
+	sampleAge.Store(1)
+	e.refreshSnapshot(e.logger)
+	assert.False(t, delivered("a"), "the sample is not delivered again to the same scraper")
+	assert.True(t, delivered("b"), "the sample delivered to a is delivered to b too")
+	assert.False(t, delivered("b"))
+
+	// a new sample
+	time.Sleep(5 * time.Millisecond)
+	sampleAge.Store(0)
+	e.refreshSnapshot(e.logger)
+	assert.True(t, delivered("a"))
+	assert.True(t, delivered("b"))
+}
+
+func hasFamily(mfs []*io_prometheus_client.MetricFamily, name string) bool {
+	return slices.ContainsFunc(mfs, func(mf *io_prometheus_client.MetricFamily) bool {
+		return mf.GetName() == name
+	})
+}
@@ -0,0 +1,85 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// WriteText writes the metric families in the text format, the same way the handler does. Sample timestamps are
+// left out, as the textfile collector of node_exporter rejects them.
+func WriteText(w io.Writer, mfs []*io_prometheus_client.MetricFamily) error {
+	return encodeMetricFamilies(w, expfmt.NewFormat(expfmt.TypeTextPlain), WithoutTimestamps(mfs))
+}
+
+// WithoutTimestamps returns the metric families without sample timestamps, for the consumers that reject them, e.g.
+// the textfile collector of node_exporter and the Pushgateway. The metric families are not modified.
+func WithoutTimestamps(mfs []*io_prometheus_client.MetricFamily) []*io_prometheus_client.MetricFamily {
+	res := make([]*io_prometheus_client.MetricFamily, 0, len(mfs))
+	for _, mf := range mfs {
+		metrics := make([]*io_prometheus_client.Metric, 0, len(mf.GetMetric()))
+		for _, m := range mf.GetMetric() {
+			metrics = append(metrics, &io_prometheus_client.Metric{
+				Label:     m.Label,
+				Gauge:     m.Gauge,
+				Counter:   m.Counter,
+				Summary:   m.Summary,
+				Untyped:   m.Untyped,
+				Histogram: m.Histogram,
+			})
+		}
+		res = append(res, &io_prometheus_client.MetricFamily{
+			Name:   mf.Name,
+			Help:   mf.Help,
+			Type:   mf.Type,
+			Unit:   mf.Unit,
+			Metric: metrics,
+		})
+	}
+	return res
+}
+
+// WriteTextFile writes the metric families in the text format to a temporary file next to path and renames it,
+// so that the textfile collector of node_exporter never reads a partially written file
+func WriteTextFile(path string, mfs []*io_prometheus_client.MetricFamily) error {
+	// the textfile collector only reads *.prom files, so the temporary file is ignored
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := WriteText(f, mfs); err != nil {
+		f.Close()
+		return err
+	}
+	// readable by node_exporter running as another user
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
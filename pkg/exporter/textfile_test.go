@@ -0,0 +1,68 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTextFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "axosyslog.prom")
+	require.NoError(t, os.WriteFile(path, []byte("stale"), 0o600))
+
+	mfs := []*io_prometheus_client.MetricFamily{
+		{
+			Name: new("syslogng_output_event_delay_sample_seconds"),
+			Help: new("Latency of the last delivered message."),
+			Type: io_prometheus_client.MetricType_GAUGE.Enum(),
+			Metric: []*io_prometheus_client.Metric{
+				{Gauge: &io_prometheus_client.Gauge{Value: new(0.5)}, TimestampMs: new(int64(1700000000000))},
+			},
+		},
+		{
+			Name: new("syslogng_events_allocated_bytes"),
+			Type: io_prometheus_client.MetricType_GAUGE.Enum(),
+			Metric: []*io_prometheus_client.Metric{
+				{Gauge: &io_prometheus_client.Gauge{Value: new(42.0)}},
+			},
+		},
+	}
+	require.NoError(t, WriteTextFile(path, mfs))
+
+	dat, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# TYPE syslogng_events_allocated_bytes gauge\n"+
+		"syslogng_events_allocated_bytes 42\n"+
+		"# HELP syslogng_output_event_delay_sample_seconds Latency of the last delivered message.\n"+
+		"# TYPE syslogng_output_event_delay_sample_seconds gauge\n"+
+		"syslogng_output_event_delay_sample_seconds 0.5\n", string(dat))
+	assert.NotNil(t, mfs[0].Metric[0].TimestampMs, "the metric families are not modified")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no leftover temporary files")
+
+	assert.Error(t, WriteTextFile(filepath.Join(dir, "missing", "axosyslog.prom"), mfs))
+}
@@ -0,0 +1,167 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpclient creates the HTTP clients of the push outputs from their configuration, modeled on the
+// http_config of Prometheus.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config is the authentication and TLS configuration of an HTTP client as it appears in the configuration file
+type Config struct {
+	BasicAuth *BasicAuth `yaml:"basic_auth"`
+	// BearerToken or the content of BearerTokenFile is sent in the Authorization header
+	BearerToken     string            `yaml:"bearer_token"`
+	BearerTokenFile string            `yaml:"bearer_token_file"`
+	TLSConfig       TLSConfig         `yaml:"tls_config"`
+	Headers         map[string]string `yaml:"headers"`
+}
+
+// BasicAuth is the basic authentication of the client, the password is either set or read from PasswordFile
+type BasicAuth struct {
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+}
+
+// TLSConfig is the TLS configuration of the client
+type TLSConfig struct {
+	// CAFile is the CA certificate bundle verifying the server (default: the system roots)
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile are the client certificate and key
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// ClientOption is an option for NewClient
+type ClientOption func(*http.Transport)
+
+// WithHTTP2Only makes the client speak HTTP/2 only, over cleartext (h2c) for http URLs, as required by gRPC
+func WithHTTP2Only() ClientOption {
+	return func(t *http.Transport) {
+		t.Protocols = new(http.Protocols)
+		t.Protocols.SetHTTP2(true)
+		t.Protocols.SetUnencryptedHTTP2(true)
+	}
+}
+
+// NewClient validates the configuration and creates an HTTP client with the timeout
+func (c Config) NewClient(timeout time.Duration, opts ...ClientOption) (*http.Client, error) {
+	if c.BasicAuth != nil && (c.BearerToken != "" || c.BearerTokenFile != "") {
+		return nil, fmt.Errorf("only one of basic_auth and bearer_token can be set")
+	}
+	if c.BearerToken != "" && c.BearerTokenFile != "" {
+		return nil, fmt.Errorf("only one of bearer_token and bearer_token_file can be set")
+	}
+	if c.BasicAuth != nil && c.BasicAuth.Password != "" && c.BasicAuth.PasswordFile != "" {
+		return nil, fmt.Errorf("only one of password and password_file can be set")
+	}
+	tlsConfig, err := c.TLSConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	for _, opt := range opts {
+		opt(transport)
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &roundTripper{cfg: c, next: transport},
+	}, nil
+}
+
+// ClientConfig validates the configuration and creates the TLS configuration of a client, e.g. of a TLS connection
+// that is not HTTP
+func (c TLSConfig) ClientConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file failed: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", c.CAFile)
+		}
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must be set together")
+	}
+	if c.CertFile != "" {
+		// the certificate is loaded on every handshake, so that it can be rotated without restarting
+		if _, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile); err != nil {
+			return nil, fmt.Errorf("loading client certificate failed: %w", err)
+		}
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+			return &cert, err
+		}
+	}
+	return cfg, nil
+}
+
+// roundTripper sets the headers and the credentials of the requests. Secrets are read from their files on every
+// request, so that they can be rotated without restarting.
+type roundTripper struct {
+	cfg  Config
+	next http.RoundTripper
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range rt.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	switch {
+	case rt.cfg.BasicAuth != nil:
+		password, err := secret(rt.cfg.BasicAuth.Password, rt.cfg.BasicAuth.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading password file failed: %w", err)
+		}
+		req.SetBasicAuth(rt.cfg.BasicAuth.Username, password)
+	case rt.cfg.BearerToken != "" || rt.cfg.BearerTokenFile != "":
+		token, err := secret(rt.cfg.BearerToken, rt.cfg.BearerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading bearer token file failed: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return rt.next.RoundTrip(req)
+}
+
+func secret(value, file string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+	dat, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(dat)), nil
+}
@@ -0,0 +1,77 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	var received http.Header
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600))
+	passwordFile := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("s3cret\n"), 0o600))
+
+	client, err := Config{
+		BasicAuth: &BasicAuth{Username: "user", PasswordFile: passwordFile},
+		TLSConfig: TLSConfig{CAFile: caFile},
+		Headers:   map[string]string{"X-Scope-OrgID": "tenant-1"},
+	}.NewClient(time.Second)
+	require.NoError(t, err)
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "tenant-1", received.Get("X-Scope-OrgID"))
+	user, password, ok := (&http.Request{Header: received}).BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "s3cret", password)
+
+	client, err = Config{}.NewClient(time.Second)
+	require.NoError(t, err)
+	_, err = client.Get(srv.URL)
+	assert.Error(t, err, "the certificate of the server is not trusted")
+}
+
+func TestConfigErrors(t *testing.T) {
+	for name, c := range map[string]Config{
+		"basic auth and bearer token": {BasicAuth: &BasicAuth{Username: "a"}, BearerToken: "b"},
+		"bearer token and file":       {BearerToken: "a", BearerTokenFile: "b"},
+		"password and file":           {BasicAuth: &BasicAuth{Password: "a", PasswordFile: "b"}},
+		"cert without key":            {TLSConfig: TLSConfig{CertFile: "a"}},
+		"missing CA file":             {TLSConfig: TLSConfig{CAFile: "/nonexistent"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := c.NewClient(time.Second)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lineoutput
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+)
+
+type label struct {
+	name, value string
+}
+
+// sample is a series of a metric family, histograms and summaries are split into their series like in the text
+// format
+type sample struct {
+	family  string
+	name    string
+	labels  []label
+	value   float64
+	counter bool
+}
+
+func (s sample) label(name string) string {
+	for _, l := range s.labels {
+		if l.name == name {
+			return l.value
+		}
+	}
+	return ""
+}
+
+func (s sample) key() string {
+	var sb strings.Builder
+	sb.WriteString(s.name)
+	for _, l := range s.labels {
+		sb.WriteString("\xfe" + l.name + "\xff" + l.value)
+	}
+	return sb.String()
+}
+
+// samples splits the metric families into samples with sorted labels, leaving out NaN and infinite values
+func samples(mfs []*io_prometheus_client.MetricFamily) []sample {
+	var res []sample
+	for _, mf := range mfs {
+		family := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			add := func(name string, value float64, counter bool, extra ...label) {
+				if math.IsNaN(value) || math.IsInf(value, 0) {
+					return
+				}
+				labels := make([]label, 0, len(m.GetLabel())+len(extra))
+				for _, l := range m.GetLabel() {
+					labels = append(labels, label{l.GetName(), l.GetValue()})
+				}
+				labels = append(labels, extra...)
+				slices.SortFunc(labels, func(a, b label) int { return cmp.Compare(a.name, b.name) })
+				res = append(res, sample{family: family, name: name, labels: labels, value: value, counter: counter})
+			}
+
+			switch mf.GetType() {
+			case io_prometheus_client.MetricType_COUNTER:
+				add(family, m.GetCounter().GetValue(), true)
+			case io_prometheus_client.MetricType_GAUGE:
+				add(family, m.GetGauge().GetValue(), false)
+			case io_prometheus_client.MetricType_UNTYPED:
+				add(family, m.GetUntyped().GetValue(), false)
+			case io_prometheus_client.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				var infSeen bool
+				for _, b := range h.GetBucket() {
+					infSeen = infSeen || math.IsInf(b.GetUpperBound(), 1)
+					add(family+"_bucket", float64(b.GetCumulativeCount()), true, label{"le", formatFloat(b.GetUpperBound())})
+				}
+				if !infSeen {
+					add(family+"_bucket", float64(h.GetSampleCount()), true, label{"le", "+Inf"})
+				}
+				add(family+"_sum", h.GetSampleSum(), true)
+				add(family+"_count", float64(h.GetSampleCount()), true)
+			case io_prometheus_client.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add(family, q.GetValue(), false, label{"quantile", formatFloat(q.GetQuantile())})
+				}
+				add(family+"_sum", s.GetSampleSum(), true)
+				add(family+"_count", float64(s.GetSampleCount()), true)
+			}
+		}
+	}
+	return res
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
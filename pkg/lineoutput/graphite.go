@@ -0,0 +1,48 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lineoutput
+
+import (
+	"strconv"
+	"time"
+)
+
+// graphite formats the samples in the Graphite plaintext protocol: <path> <value> <timestamp>. Counters are sent
+// as they are, Graphite computes their rate with nonNegativeDerivative.
+type graphite struct {
+	prefix    string
+	templates []template
+	tagged    bool
+}
+
+func (g *graphite) format(samples []sample, now time.Time) ([]string, func()) {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	lines := make([]string, 0, len(samples))
+	for _, s := range samples {
+		path := g.prefix + pathOf(g.templates, s, g.tagged)
+		lines = append(lines, path+" "+strconv.FormatFloat(s.value, 'g', -1, 64)+" "+ts)
+	}
+	return lines, func() {}
+}
+
+// pathOf renders the first template matching the family of the sample, or the default template
+func pathOf(templates []template, s sample, tagged bool) string {
+	for _, t := range templates {
+		if t.match.MatchString(s.family) {
+			return t.path(s, tagged)
+		}
+	}
+	return defaultTemplate.path(s, tagged)
+}
@@ -0,0 +1,245 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lineoutput pushes the metrics in line based protocols, Graphite plaintext and StatsD, for legacy
+// monitoring systems.
+package lineoutput
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/exporter"
+)
+
+const (
+	DefaultInterval = 30 * time.Second
+	DefaultTimeout  = 10 * time.Second
+
+	// maxDatagramSize keeps the UDP datagrams below the usual MTU, like the DogStatsD clients
+	maxDatagramSize = 1432
+)
+
+// Config is the configuration of a Graphite or StatsD output as it appears in the configuration file
+type Config struct {
+	// Address is the host:port of the server
+	Address string `yaml:"address"`
+	// Network is tcp or udp (default: tcp for Graphite, udp for StatsD)
+	Network string `yaml:"network"`
+	// Interval is the time between gathering the metrics (default: DefaultInterval)
+	Interval time.Duration `yaml:"interval"`
+	// Timeout is the timeout of connecting and sending (default: DefaultTimeout)
+	Timeout time.Duration `yaml:"timeout"`
+	// Prefix is prepended to every path or name, e.g. "syslogng."
+	Prefix string `yaml:"prefix"`
+	// Templates build the Graphite paths, and the names in the plain StatsD flavor
+	Templates []TemplateConfig `yaml:"templates"`
+	// Tagged sends the labels not used by the templates as Graphite tags instead of path nodes (Graphite only)
+	Tagged bool `yaml:"tagged"`
+	// Flavor is dogstatsd (labels as tags) or statsd (labels in the name) (StatsD only, default: dogstatsd)
+	Flavor string `yaml:"flavor"`
+}
+
+// formatter converts the samples to lines, commit is called once the lines were sent
+type formatter interface {
+	format(samples []sample, now time.Time) (lines []string, commit func())
+}
+
+// Pusher gathers the metrics at the interval and sends them to the server. A new connection is opened for every
+// push, failed pushes are not retried.
+type Pusher struct {
+	output    string
+	cfg       Config
+	gather    exporter.GatherFunc
+	formatter formatter
+	logger    *slog.Logger
+	now       func() time.Time
+
+	pushes      *prometheus.CounterVec
+	lastSuccess prometheus.Gauge
+}
+
+// Option is an option for NewGraphite and NewStatsD
+type Option func(*Pusher)
+
+// WithLogger sets the logger of the pusher (default: slog.Default)
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Pusher) {
+		p.logger = logger
+	}
+}
+
+// NewGraphite creates a pusher sending the metrics in the Graphite plaintext protocol
+func NewGraphite(cfg Config, gather exporter.GatherFunc, opts ...Option) (*Pusher, error) {
+	if cfg.Network == "" {
+		cfg.Network = "tcp"
+	}
+	templates, err := parseTemplates(cfg.Templates)
+	if err != nil {
+		return nil, err
+	}
+	return newPusher("graphite", cfg, gather, &graphite{prefix: cfg.Prefix, templates: templates, tagged: cfg.Tagged}, opts)
+}
+
+// NewStatsD creates a pusher sending the metrics in the StatsD protocol
+func NewStatsD(cfg Config, gather exporter.GatherFunc, opts ...Option) (*Pusher, error) {
+	if cfg.Network == "" {
+		cfg.Network = "udp"
+	}
+	if cfg.Flavor == "" {
+		cfg.Flavor = FlavorDogStatsD
+	}
+	if cfg.Flavor != FlavorDogStatsD && cfg.Flavor != FlavorStatsD {
+		return nil, fmt.Errorf("invalid StatsD flavor %q, must be %s or %s", cfg.Flavor, FlavorDogStatsD, FlavorStatsD)
+	}
+	templates, err := parseTemplates(cfg.Templates)
+	if err != nil {
+		return nil, err
+	}
+	return newPusher("statsd", cfg, gather, &statsd{prefix: cfg.Prefix, flavor: cfg.Flavor, templates: templates}, opts)
+}
+
+func parseTemplates(cfgs []TemplateConfig) ([]template, error) {
+	templates := make([]template, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		t, err := parseTemplate(cfg)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+func newPusher(output string, cfg Config, gather exporter.GatherFunc, f formatter, opts []Option) (*Pusher, error) {
+	if _, _, err := net.SplitHostPort(cfg.Address); err != nil {
+		return nil, fmt.Errorf("invalid %s address %q: %w", output, cfg.Address, err)
+	}
+	if cfg.Network != "tcp" && cfg.Network != "udp" {
+		return nil, fmt.Errorf("invalid %s network %q, must be tcp or udp", output, cfg.Network)
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	p := &Pusher{
+		output:    output,
+		cfg:       cfg,
+		gather:    gather,
+		formatter: f,
+		logger:    slog.Default(),
+		now:       time.Now,
+		pushes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "syslogng_exporter_" + output + "_pushes_total",
+			Help: "Number of pushes to the " + output + " server by result: sent or failed.",
+		}, []string{"result"}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "syslogng_exporter_" + output + "_last_success_timestamp_seconds",
+			Help: "Time of the last successful push to the " + output + " server, in Unix time.",
+		}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	for _, result := range []string{"sent", "failed"} {
+		p.pushes.WithLabelValues(result)
+	}
+	return p, nil
+}
+
+// Run gathers and pushes the metrics until ctx is done
+func (p *Pusher) Run(ctx context.Context) {
+	p.logger.Info("pushing metrics", "output", p.output, "address", p.cfg.Address, "network", p.cfg.Network, "interval", p.cfg.Interval)
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		p.push(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pusher) push(ctx context.Context) {
+	mfs, _ := p.gather(ctx)
+	lines, commit := p.formatter.format(samples(mfs), p.now())
+	if err := p.send(ctx, lines); err != nil {
+		p.logger.Warn("pushing metrics failed", "output", p.output, "address", p.cfg.Address, "error", err)
+		p.pushes.WithLabelValues("failed").Inc()
+		return
+	}
+	commit()
+	p.pushes.WithLabelValues("sent").Inc()
+	p.lastSuccess.Set(float64(p.now().UnixNano()) / 1e9)
+	p.logger.Debug("pushed metrics", "output", p.output, "lines", len(lines))
+}
+
+// send writes the lines over a new connection. Over UDP, the lines are batched into datagrams.
+func (p *Pusher) send(ctx context.Context, lines []string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	dialer := net.Dialer{Timeout: p.cfg.Timeout}
+	conn, err := dialer.DialContext(ctx, p.cfg.Network, p.cfg.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(p.cfg.Timeout)); err != nil {
+		return err
+	}
+
+	if p.cfg.Network == "tcp" {
+		_, err := conn.Write([]byte(strings.Join(lines, "\n") + "\n"))
+		return err
+	}
+	var datagram []byte
+	for _, line := range lines {
+		if len(datagram) > 0 && len(datagram)+1+len(line) > maxDatagramSize {
+			if _, err := conn.Write(datagram); err != nil {
+				return err
+			}
+			datagram = datagram[:0]
+		}
+		if len(datagram) > 0 {
+			datagram = append(datagram, '\n')
+		}
+		datagram = append(datagram, line...)
+	}
+	_, err = conn.Write(datagram)
+	return err
+}
+
+// Describe implements prometheus.Collector for the syslogng_exporter_graphite_* or syslogng_exporter_statsd_* metrics
+func (p *Pusher) Describe(ch chan<- *prometheus.Desc) {
+	p.pushes.Describe(ch)
+	p.lastSuccess.Describe(ch)
+}
+
+// Collect implements prometheus.Collector for the syslogng_exporter_graphite_* or syslogng_exporter_statsd_* metrics
+func (p *Pusher) Collect(ch chan<- prometheus.Metric) {
+	p.pushes.Collect(ch)
+	p.lastSuccess.Collect(ch)
+}
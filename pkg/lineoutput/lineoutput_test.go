@@ -0,0 +1,195 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lineoutput
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/internal/metrictest"
+)
+
+const input = `# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{id="d_network#0",driver="afsocket",result="delivered"} %d
+# TYPE syslogng_output_unreachable gauge
+syslogng_output_unreachable{id="d_network#0",driver="afsocket"} 0
+# TYPE syslogng_delta gauge
+syslogng_delta -2.5
+# TYPE syslogng_delay_seconds histogram
+syslogng_delay_seconds_bucket{le="0.5"} 2
+syslogng_delay_seconds_sum 1.5
+syslogng_delay_seconds_count 3
+`
+
+func TestGraphite(t *testing.T) {
+	templates, err := parseTemplates([]TemplateConfig{
+		{Match: "syslogng_output_.*", Template: "{driver}.{id}.{__name__}.{host}"},
+	})
+	require.NoError(t, err)
+	g := &graphite{prefix: "syslogng.", templates: templates}
+
+	lines, _ := g.format(samples(metrictest.Parse(t, fmt.Sprintf(input, 10))), time.Unix(100, 0))
+	assert.Equal(t, []string{
+		"syslogng.afsocket.d_network_0.syslogng_output_events_total.none.result.delivered 10 100",
+		"syslogng.afsocket.d_network_0.syslogng_output_unreachable.none 0 100",
+		"syslogng.syslogng_delta -2.5 100",
+		"syslogng.syslogng_delay_seconds_bucket.le.0_5 2 100",
+		"syslogng.syslogng_delay_seconds_bucket.le._Inf 3 100",
+		"syslogng.syslogng_delay_seconds_sum 1.5 100",
+		"syslogng.syslogng_delay_seconds_count 3 100",
+	}, lines)
+
+	g.tagged = true
+	lines, _ = g.format(samples(metrictest.Parse(t, fmt.Sprintf(input, 10))), time.Unix(100, 0))
+	assert.Equal(t, "syslogng.afsocket.d_network_0.syslogng_output_events_total.none;result=delivered 10 100", lines[0])
+	assert.Equal(t, "syslogng.syslogng_delay_seconds_bucket;le=+Inf 3 100", lines[4])
+
+	for _, cfg := range []TemplateConfig{
+		{Template: ""},
+		{Template: "{id"},
+		{Template: "{not-a-label}"},
+		{Match: "(", Template: "{__name__}"},
+	} {
+		_, err := parseTemplate(cfg)
+		assert.Error(t, err, cfg)
+	}
+}
+
+func TestStatsD(t *testing.T) {
+	s := &statsd{prefix: "axosyslog.", flavor: FlavorDogStatsD}
+
+	// the first snapshot only sets the baseline of the counters
+	lines, commit := s.format(samples(metrictest.Parse(t, fmt.Sprintf(input, 10))), time.Unix(100, 0))
+	assert.Equal(t, []string{
+		"axosyslog.syslogng_output_unreachable:0|g|#driver:afsocket,id:d_network_0",
+		"axosyslog.syslogng_delta:0|g",
+		"axosyslog.syslogng_delta:-2.5|g",
+	}, lines)
+	commit()
+
+	lines, commit = s.format(samples(metrictest.Parse(t, fmt.Sprintf(input, 25))), time.Unix(130, 0))
+	assert.Equal(t, "axosyslog.syslogng_output_events_total:15|c|#driver:afsocket,id:d_network_0,result:delivered", lines[0])
+	assert.Contains(t, lines, "axosyslog.syslogng_delay_seconds_count:0|c")
+	assert.Contains(t, lines, "axosyslog.syslogng_delay_seconds_bucket:0|c|#le:+Inf")
+	// not committed, e.g. sending failed
+	_ = commit
+
+	// the delta is still computed from the last sent snapshot, and a reset counts from zero
+	lines, _ = s.format(samples(metrictest.Parse(t, fmt.Sprintf(input, 30))), time.Unix(160, 0))
+	assert.Equal(t, "axosyslog.syslogng_output_events_total:20|c|#driver:afsocket,id:d_network_0,result:delivered", lines[0])
+	lines, _ = s.format(samples(metrictest.Parse(t, fmt.Sprintf(input, 4))), time.Unix(160, 0))
+	assert.Equal(t, "axosyslog.syslogng_output_events_total:4|c|#driver:afsocket,id:d_network_0,result:delivered", lines[0])
+
+	s.flavor = FlavorStatsD
+	lines, _ = s.format(samples(metrictest.Parse(t, fmt.Sprintf(input, 30))), time.Unix(160, 0))
+	assert.Equal(t, "axosyslog.syslogng_output_events_total.driver.afsocket.id.d_network_0.result.delivered:20|c", lines[0])
+
+	// the separators of the protocol are replaced in the name
+	lines, _ = s.format([]sample{{
+		name:   "syslogng_output_unreachable",
+		labels: []label{{name: "driver_instance", value: "tcp,10.0.0.1:514"}},
+		value:  1,
+	}}, time.Unix(160, 0))
+	assert.Equal(t, []string{"axosyslog.syslogng_output_unreachable.driver_instance.tcp_10_0_0_1_514:1|g"}, lines)
+}
+
+func TestGraphitePusherTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	received := make(chan string, 100)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				received <- scanner.Text()
+			}
+			conn.Close()
+		}
+	}()
+
+	p, err := NewGraphite(Config{Address: ln.Addr().String(), Interval: time.Hour}, func(context.Context) ([]*io_prometheus_client.MetricFamily, error) {
+		return metrictest.Parse(t, fmt.Sprintf(input, 10))[2:3], nil
+	}, WithLogger(slog.New(slog.DiscardHandler)))
+	require.NoError(t, err)
+	p.now = func() time.Time { return time.Unix(100, 0) }
+	p.push(context.Background())
+
+	select {
+	case line := <-received:
+		assert.Equal(t, "syslogng_delta -2.5 100", line)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no line received")
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.pushes.WithLabelValues("sent")))
+}
+
+func TestStatsDPusherUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var mfs []*io_prometheus_client.MetricFamily
+	for i := range 100 {
+		mfs = append(mfs, &io_prometheus_client.MetricFamily{
+			Name:   new(fmt.Sprintf("syslogng_gauge_with_a_rather_long_name_%03d", i)),
+			Type:   io_prometheus_client.MetricType_GAUGE.Enum(),
+			Metric: []*io_prometheus_client.Metric{{Gauge: &io_prometheus_client.Gauge{Value: new(1.0)}}},
+		})
+	}
+	p, err := NewStatsD(Config{Address: conn.LocalAddr().String()}, func(context.Context) ([]*io_prometheus_client.MetricFamily, error) {
+		return mfs, nil
+	}, WithLogger(slog.New(slog.DiscardHandler)))
+	require.NoError(t, err)
+	p.push(context.Background())
+
+	var lines []string
+	buf := make([]byte, 65536)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	for len(lines) < 100 {
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, n, maxDatagramSize)
+		lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+	}
+	assert.Len(t, lines, 100)
+	assert.Equal(t, "syslogng_gauge_with_a_rather_long_name_000:1|g", lines[0])
+	assert.Equal(t, "syslogng_gauge_with_a_rather_long_name_099:1|g", lines[99])
+}
+
+func TestNewInvalid(t *testing.T) {
+	gather := func(context.Context) ([]*io_prometheus_client.MetricFamily, error) { return nil, nil }
+	_, err := NewGraphite(Config{Address: "graphite"}, gather)
+	assert.Error(t, err)
+	_, err = NewGraphite(Config{Address: "graphite:2003", Network: "unix"}, gather)
+	assert.Error(t, err)
+	_, err = NewStatsD(Config{Address: "statsd:8125", Flavor: "influx"}, gather)
+	assert.Error(t, err)
+}
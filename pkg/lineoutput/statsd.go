@@ -0,0 +1,114 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lineoutput
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FlavorDogStatsD = "dogstatsd"
+	FlavorStatsD    = "statsd"
+)
+
+// statsd formats the samples in the StatsD protocol. Counters are sent as the delta since the previous snapshot,
+// so the first snapshot of a series only sets the baseline. Gauges are sent as they are. The labels are sent as
+// tags in the DogStatsD flavor, and rendered into the name by the templates in the plain StatsD flavor.
+type statsd struct {
+	prefix    string
+	flavor    string
+	templates []template
+	previous  map[string]float64
+}
+
+func (s *statsd) format(samples []sample, _ time.Time) ([]string, func()) {
+	current := make(map[string]float64, len(samples))
+	var lines []string
+	for _, smp := range samples {
+		name := s.prefix + smp.name
+		var tags string
+		if s.flavor == FlavorDogStatsD {
+			tags = dogStatsDTags(smp.labels)
+		} else {
+			name = s.prefix + pathOf(s.templates, smp, false)
+		}
+		name = sanitizeStatsDName(name)
+
+		if !smp.counter {
+			if smp.value < 0 {
+				// a signed value would change the gauge instead of setting it
+				lines = append(lines, name+":0|g"+tags)
+			}
+			lines = append(lines, name+":"+formatValue(smp.value)+"|g"+tags)
+			continue
+		}
+
+		key := smp.key()
+		current[key] = smp.value
+		prev, found := s.previous[key]
+		if !found {
+			continue
+		}
+		delta := smp.value - prev
+		if delta < 0 {
+			// the counter was reset since the previous snapshot
+			delta = smp.value
+		}
+		lines = append(lines, name+":"+formatValue(delta)+"|c"+tags)
+	}
+	return lines, func() { s.previous = current }
+}
+
+func dogStatsDTags(labels []label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	tags := make([]string, 0, len(labels))
+	for _, l := range labels {
+		tags = append(tags, l.name+":"+sanitizeDogStatsDTag(l.value))
+	}
+	return "|#" + strings.Join(tags, ",")
+}
+
+// sanitizeStatsDName replaces the characters that separate the name from the value, the type and the sample rate
+// in the StatsD protocol, e.g. the colon of a host:port label value rendered into the name
+func sanitizeStatsDName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '\n', '\r':
+			return '_'
+		default:
+			return r
+		}
+	}, s)
+}
+
+// sanitizeDogStatsDTag replaces the characters that separate the fields of the DogStatsD protocol
+func sanitizeDogStatsDTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ',', '|', '#', '\n', '\r':
+			return '_'
+		default:
+			return r
+		}
+	}, s)
+}
+
+func formatValue(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,143 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lineoutput
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// nameLabel refers to the name of the series in the path templates
+const nameLabel = "__name__"
+
+// TemplateConfig builds the Graphite path of the series of the metric families matching a regular expression
+type TemplateConfig struct {
+	// Match is the regular expression matching the whole name of the metric family (default: every family)
+	Match string `yaml:"match"`
+	// Template is the path with {label} placeholders, {__name__} being the name of the series,
+	// e.g. syslogng.{host}.{id}.{__name__}
+	Template string `yaml:"template"`
+}
+
+type templatePart struct {
+	literal string
+	label   string
+}
+
+type template struct {
+	match      *regexp.Regexp
+	parts      []templatePart
+	referenced map[string]bool
+}
+
+// defaultTemplate is the path of the series not matching any template: the name of the series followed by the
+// labels
+var defaultTemplate = template{parts: []templatePart{{label: nameLabel}}, referenced: map[string]bool{nameLabel: true}}
+
+func parseTemplate(cfg TemplateConfig) (template, error) {
+	t := template{referenced: make(map[string]bool)}
+	match := cfg.Match
+	if match == "" {
+		match = ".*"
+	}
+	var err error
+	if t.match, err = regexp.Compile("^(?:" + match + ")$"); err != nil {
+		return t, fmt.Errorf("invalid match %q: %w", cfg.Match, err)
+	}
+	if cfg.Template == "" {
+		return t, fmt.Errorf("empty template")
+	}
+	rest := cfg.Template
+	for rest != "" {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			t.parts = append(t.parts, templatePart{literal: rest})
+			break
+		}
+		if open > 0 {
+			t.parts = append(t.parts, templatePart{literal: rest[:open]})
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return t, fmt.Errorf("unclosed placeholder in template %q", cfg.Template)
+		}
+		label := rest[open+1 : open+end]
+		if label != nameLabel && !model.LabelName(label).IsValidLegacy() {
+			return t, fmt.Errorf("invalid label %q in template %q", label, cfg.Template)
+		}
+		t.parts = append(t.parts, templatePart{label: label})
+		t.referenced[label] = true
+		rest = rest[open+end+1:]
+	}
+	return t, nil
+}
+
+// path renders the template for the series. Labels missing from the series are rendered as "none". Labels not
+// referenced by the template are appended as .name.value nodes, or as name=value tags if tagged.
+func (t template) path(s sample, tagged bool) string {
+	var sb strings.Builder
+	for _, p := range t.parts {
+		if p.label == "" {
+			sb.WriteString(p.literal)
+			continue
+		}
+		value := s.name
+		if p.label != nameLabel {
+			value = s.label(p.label)
+		}
+		if value == "" {
+			value = "none"
+		}
+		sb.WriteString(sanitizeNode(value))
+	}
+	for _, l := range s.labels {
+		if t.referenced[l.name] || l.value == "" {
+			continue
+		}
+		if tagged {
+			sb.WriteString(";" + l.name + "=" + sanitizeTag(l.value))
+		} else {
+			sb.WriteString("." + l.name + "." + sanitizeNode(l.value))
+		}
+	}
+	return sb.String()
+}
+
+// sanitizeNode replaces the characters that are not safe in a node of a Graphite path, most importantly dots
+func sanitizeNode(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// sanitizeTag replaces the characters that are not allowed in the value of a Graphite tag
+func sanitizeTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ';', '~', ' ', '\t', '\n', '\r':
+			return '_'
+		default:
+			return r
+		}
+	}, s)
+}
@@ -0,0 +1,327 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the OTLP messages (opentelemetry.proto.collector.metrics.v1 and opentelemetry.proto.metrics.v1)
+const (
+	requestResourceMetrics = 1
+
+	resourceMetricsResource = 1
+	resourceMetricsScope    = 2
+
+	resourceAttributes = 1
+
+	scopeMetricsScope   = 1
+	scopeMetricsMetrics = 2
+
+	scopeName    = 1
+	scopeVersion = 2
+
+	metricName        = 1
+	metricDescription = 2
+	metricUnit        = 3
+	metricGauge       = 5
+	metricSum         = 7
+	metricHistogram   = 9
+	metricSummary     = 11
+
+	dataPoints             = 1
+	aggregationTemporality = 2
+	sumIsMonotonic         = 3
+
+	numberPointStartTime  = 2
+	numberPointTime       = 3
+	numberPointDouble     = 4
+	numberPointAttributes = 7
+
+	histogramPointStartTime  = 2
+	histogramPointTime       = 3
+	histogramPointCount      = 4
+	histogramPointSum        = 5
+	histogramPointBuckets    = 6
+	histogramPointBounds     = 7
+	histogramPointAttributes = 9
+
+	summaryPointStartTime  = 2
+	summaryPointTime       = 3
+	summaryPointCount      = 4
+	summaryPointSum        = 5
+	summaryPointQuantiles  = 6
+	summaryPointAttributes = 7
+
+	quantileQuantile = 1
+	quantileValue    = 2
+
+	keyValueKey   = 1
+	keyValueValue = 2
+
+	anyValueString = 1
+
+	temporalityCumulative = 2
+)
+
+// units maps the units of the metrics catalog to UCUM units, like the Prometheus receiver of the OpenTelemetry collector
+var units = map[string]string{
+	"seconds": "s",
+	"bytes":   "By",
+}
+
+// startTimes keeps track of the start of the cumulative series, which is the time they were first seen or the
+// time they were last found reset (unless the series has a created timestamp)
+type startTimes struct {
+	mu     sync.Mutex
+	series map[string]*seriesStart
+}
+
+type seriesStart struct {
+	start time.Time
+	last  float64
+	seen  time.Time
+}
+
+// staleSeries is the time after which the start time of series that disappeared is forgotten
+const staleSeries = time.Hour
+
+func (s *startTimes) startOf(key string, value float64, now time.Time) time.Time {
+	st := s.series[key]
+	switch {
+	case st == nil:
+		st = &seriesStart{start: now}
+		s.series[key] = st
+	case value < st.last:
+		// reset after the previous observation
+		st.start = st.seen
+	}
+	st.last = value
+	st.seen = now
+	return st.start
+}
+
+func (s *startTimes) forgetStale(now time.Time) {
+	maps.DeleteFunc(s.series, func(_ string, st *seriesStart) bool {
+		return now.Sub(st.seen) > staleSeries
+	})
+}
+
+// encoder converts the metric families to OTLP export requests
+type encoder struct {
+	resource     []*io_prometheus_client.LabelPair
+	scopeVersion string
+	starts       startTimes
+}
+
+func newEncoder(resourceAttributes map[string]string, version string) *encoder {
+	e := &encoder{scopeVersion: version, starts: startTimes{series: make(map[string]*seriesStart)}}
+	for _, key := range slices.Sorted(maps.Keys(resourceAttributes)) {
+		e.resource = append(e.resource, &io_prometheus_client.LabelPair{Name: new(key), Value: new(resourceAttributes[key])})
+	}
+	return e
+}
+
+// encode converts the metric families to an ExportMetricsServiceRequest. Counters become cumulative monotonic sums,
+// gauges and untyped metrics become gauges, histograms and summaries are converted to their OTLP counterparts.
+func (e *encoder) encode(mfs []*io_prometheus_client.MetricFamily, now time.Time) []byte {
+	e.starts.mu.Lock()
+	defer e.starts.mu.Unlock()
+
+	var metrics []byte
+	for _, mf := range mfs {
+		if m := e.encodeMetric(mf, now); m != nil {
+			metrics = protowire.AppendTag(metrics, scopeMetricsMetrics, protowire.BytesType)
+			metrics = protowire.AppendBytes(metrics, m)
+		}
+	}
+	e.starts.forgetStale(now)
+
+	var scope []byte
+	scope = appendString(scope, scopeName, scopeNameValue)
+	scope = appendString(scope, scopeVersion, e.scopeVersion)
+
+	var scopeMetrics []byte
+	scopeMetrics = appendMessage(scopeMetrics, scopeMetricsScope, scope)
+	scopeMetrics = append(scopeMetrics, metrics...)
+
+	var resource []byte
+	resource = appendAttributes(resource, resourceAttributes, e.resource)
+
+	var resourceMetrics []byte
+	resourceMetrics = appendMessage(resourceMetrics, resourceMetricsResource, resource)
+	resourceMetrics = appendMessage(resourceMetrics, resourceMetricsScope, scopeMetrics)
+
+	return appendMessage(nil, requestResourceMetrics, resourceMetrics)
+}
+
+func (e *encoder) encodeMetric(mf *io_prometheus_client.MetricFamily, now time.Time) []byte {
+	name := mf.GetName()
+	var points []byte
+	for _, m := range mf.GetMetric() {
+		ts := now
+		if m.TimestampMs != nil {
+			ts = time.UnixMilli(m.GetTimestampMs())
+		}
+		key := name + seriesKey(m.GetLabel())
+
+		var p []byte
+		switch mf.GetType() {
+		case io_prometheus_client.MetricType_COUNTER:
+			start := e.starts.startOf(key, m.GetCounter().GetValue(), now)
+			if m.GetCounter().CreatedTimestamp != nil {
+				start = m.GetCounter().GetCreatedTimestamp().AsTime()
+			}
+			p = appendFixed64(p, numberPointStartTime, uint64(start.UnixNano()))
+			p = appendFixed64(p, numberPointTime, uint64(ts.UnixNano()))
+			p = appendDouble(p, numberPointDouble, m.GetCounter().GetValue())
+			p = appendAttributes(p, numberPointAttributes, m.GetLabel())
+		case io_prometheus_client.MetricType_GAUGE, io_prometheus_client.MetricType_UNTYPED:
+			v := m.GetGauge().GetValue()
+			if mf.GetType() == io_prometheus_client.MetricType_UNTYPED {
+				v = m.GetUntyped().GetValue()
+			}
+			p = appendFixed64(p, numberPointTime, uint64(ts.UnixNano()))
+			p = appendDouble(p, numberPointDouble, v)
+			p = appendAttributes(p, numberPointAttributes, m.GetLabel())
+		case io_prometheus_client.MetricType_HISTOGRAM:
+			h := m.GetHistogram()
+			start := e.starts.startOf(key, float64(h.GetSampleCount()), now)
+			if h.CreatedTimestamp != nil {
+				start = h.GetCreatedTimestamp().AsTime()
+			}
+			var bounds, counts []byte
+			var prev uint64
+			for _, b := range h.GetBucket() {
+				if !math.IsInf(b.GetUpperBound(), 1) {
+					bounds = protowire.AppendFixed64(bounds, math.Float64bits(b.GetUpperBound()))
+				}
+				counts = protowire.AppendFixed64(counts, b.GetCumulativeCount()-prev)
+				prev = b.GetCumulativeCount()
+			}
+			if len(h.GetBucket()) == 0 || !math.IsInf(h.GetBucket()[len(h.GetBucket())-1].GetUpperBound(), 1) {
+				counts = protowire.AppendFixed64(counts, h.GetSampleCount()-prev)
+			}
+			p = appendFixed64(p, histogramPointStartTime, uint64(start.UnixNano()))
+			p = appendFixed64(p, histogramPointTime, uint64(ts.UnixNano()))
+			p = appendFixed64(p, histogramPointCount, h.GetSampleCount())
+			p = appendDouble(p, histogramPointSum, h.GetSampleSum())
+			p = appendMessage(p, histogramPointBuckets, counts)
+			p = appendMessage(p, histogramPointBounds, bounds)
+			p = appendAttributes(p, histogramPointAttributes, m.GetLabel())
+		case io_prometheus_client.MetricType_SUMMARY:
+			s := m.GetSummary()
+			start := e.starts.startOf(key, float64(s.GetSampleCount()), now)
+			if s.CreatedTimestamp != nil {
+				start = s.GetCreatedTimestamp().AsTime()
+			}
+			p = appendFixed64(p, summaryPointStartTime, uint64(start.UnixNano()))
+			p = appendFixed64(p, summaryPointTime, uint64(ts.UnixNano()))
+			p = appendFixed64(p, summaryPointCount, s.GetSampleCount())
+			p = appendDouble(p, summaryPointSum, s.GetSampleSum())
+			for _, q := range s.GetQuantile() {
+				var qv []byte
+				qv = appendDouble(qv, quantileQuantile, q.GetQuantile())
+				qv = appendDouble(qv, quantileValue, q.GetValue())
+				p = appendMessage(p, summaryPointQuantiles, qv)
+			}
+			p = appendAttributes(p, summaryPointAttributes, m.GetLabel())
+		default:
+			continue
+		}
+		points = appendMessage(points, dataPoints, p)
+	}
+	if points == nil {
+		return nil
+	}
+
+	var data []byte
+	var dataField protowire.Number
+	switch mf.GetType() {
+	case io_prometheus_client.MetricType_COUNTER:
+		dataField = metricSum
+		data = append(points, protowire.AppendTag(nil, aggregationTemporality, protowire.VarintType)...)
+		data = protowire.AppendVarint(data, temporalityCumulative)
+		data = protowire.AppendTag(data, sumIsMonotonic, protowire.VarintType)
+		data = protowire.AppendVarint(data, 1)
+	case io_prometheus_client.MetricType_HISTOGRAM:
+		dataField = metricHistogram
+		data = append(points, protowire.AppendTag(nil, aggregationTemporality, protowire.VarintType)...)
+		data = protowire.AppendVarint(data, temporalityCumulative)
+	case io_prometheus_client.MetricType_SUMMARY:
+		dataField, data = metricSummary, points
+	default:
+		dataField, data = metricGauge, points
+	}
+
+	var metric []byte
+	metric = appendString(metric, metricName, name)
+	metric = appendString(metric, metricDescription, mf.GetHelp())
+	if unit, found := units[mf.GetUnit()]; found {
+		metric = appendString(metric, metricUnit, unit)
+	} else if mf.GetUnit() != "" {
+		metric = appendString(metric, metricUnit, mf.GetUnit())
+	}
+	return appendMessage(metric, dataField, data)
+}
+
+func appendMessage(b []byte, num protowire.Number, m []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendFixed64(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, v)
+}
+
+func appendDouble(b []byte, num protowire.Number, v float64) []byte {
+	return appendFixed64(b, num, math.Float64bits(v))
+}
+
+func appendAttributes(b []byte, num protowire.Number, labels []*io_prometheus_client.LabelPair) []byte {
+	for _, l := range labels {
+		var kv []byte
+		kv = appendString(kv, keyValueKey, l.GetName())
+		kv = appendMessage(kv, keyValueValue, protowire.AppendString(protowire.AppendTag(nil, anyValueString, protowire.BytesType), l.GetValue()))
+		b = appendMessage(b, num, kv)
+	}
+	return b
+}
+
+func seriesKey(labels []*io_prometheus_client.LabelPair) string {
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, l.GetName()+"\xff"+l.GetValue())
+	}
+	slices.Sort(pairs)
+	return "\xfe" + strings.Join(pairs, "\xfe")
+}
@@ -0,0 +1,331 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otlp pushes the metrics to an OpenTelemetry collector over OTLP/HTTP or OTLP/gRPC, for shops that
+// standardized on OpenTelemetry.
+//
+// Reference: https://opentelemetry.io/docs/specs/otlp/
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/exporter"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/httpclient"
+)
+
+const (
+	ProtocolHTTP = "http/protobuf"
+	ProtocolGRPC = "grpc"
+
+	CompressionGzip = "gzip"
+	CompressionNone = "none"
+
+	DefaultInterval   = 30 * time.Second
+	DefaultTimeout    = 10 * time.Second
+	DefaultMinBackoff = time.Second
+
+	httpPath       = "/v1/metrics"
+	grpcPath       = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+	scopeNameValue = "github.com/axoflow/axosyslog-metrics-exporter"
+	userAgent      = "axosyslog-metrics-exporter"
+)
+
+// Config is the configuration of the OTLP endpoint as it appears in the configuration file
+type Config struct {
+	// Endpoint is the URL of the collector. For http/protobuf the path defaults to /v1/metrics, for grpc the http
+	// scheme means cleartext HTTP/2 and the path is ignored.
+	Endpoint string `yaml:"endpoint"`
+	// Protocol is either http/protobuf or grpc (default: http/protobuf)
+	Protocol string `yaml:"protocol"`
+	// Compression is either gzip or none (default: gzip)
+	Compression string `yaml:"compression"`
+	// Interval is the time between gathering the metrics (default: DefaultInterval)
+	Interval time.Duration `yaml:"interval"`
+	// Timeout is the timeout of a request (default: DefaultTimeout)
+	Timeout time.Duration `yaml:"timeout"`
+	// ResourceAttributes are the attributes of the resource of the exported metrics, e.g. service.name or host.name
+	ResourceAttributes map[string]string `yaml:"resource_attributes"`
+	HTTP               httpclient.Config `yaml:",inline"`
+	// MinBackoff is the time before retrying a failed export, which doubles on every failure (default: DefaultMinBackoff)
+	MinBackoff time.Duration `yaml:"min_backoff"`
+}
+
+// Pusher gathers the metrics at the interval and exports them to the collector. Exports that fail with a
+// retryable error are retried with backoff until the next interval: as the sums are cumulative, the next export
+// supersedes the failed one.
+type Pusher struct {
+	cfg     Config
+	url     string
+	client  *http.Client
+	gather  exporter.GatherFunc
+	encoder *encoder
+	version string
+	logger  *slog.Logger
+	now     func() time.Time
+
+	requests    *prometheus.CounterVec
+	lastSuccess prometheus.Gauge
+}
+
+// Option is an option for New
+type Option func(*Pusher)
+
+// WithLogger sets the logger of the pusher (default: slog.Default)
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Pusher) {
+		p.logger = logger
+	}
+}
+
+// WithVersion sets the version of the instrumentation scope of the exported metrics
+func WithVersion(version string) Option {
+	return func(p *Pusher) {
+		p.version = version
+	}
+}
+
+func New(cfg Config, gather exporter.GatherFunc, opts ...Option) (*Pusher, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Protocol == "" {
+		cfg.Protocol = ProtocolHTTP
+	}
+	if cfg.Compression == "" {
+		cfg.Compression = CompressionGzip
+	}
+	if cfg.Compression != CompressionGzip && cfg.Compression != CompressionNone {
+		return nil, fmt.Errorf("invalid OTLP compression %q, must be %s or %s", cfg.Compression, CompressionGzip, CompressionNone)
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = DefaultMinBackoff
+	}
+
+	var clientOpts []httpclient.ClientOption
+	switch cfg.Protocol {
+	case ProtocolHTTP:
+		if u.Path == "" || u.Path == "/" {
+			u.Path = httpPath
+		}
+	case ProtocolGRPC:
+		u.Path, u.RawQuery = grpcPath, ""
+		clientOpts = append(clientOpts, httpclient.WithHTTP2Only())
+	default:
+		return nil, fmt.Errorf("invalid OTLP protocol %q, must be %s or %s", cfg.Protocol, ProtocolHTTP, ProtocolGRPC)
+	}
+
+	p := &Pusher{
+		cfg:    cfg,
+		url:    u.String(),
+		gather: gather,
+		logger: slog.Default(),
+		now:    time.Now,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "syslogng_exporter_otlp_requests_total",
+			Help: "Number of OTLP export requests by result: sent, retried or dropped.",
+		}, []string{"result"}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "syslogng_exporter_otlp_last_success_timestamp_seconds",
+			Help: "Time of the last successful OTLP export request, in Unix time.",
+		}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.client, err = cfg.HTTP.NewClient(cfg.Timeout, clientOpts...); err != nil {
+		return nil, err
+	}
+	p.encoder = newEncoder(cfg.ResourceAttributes, p.version)
+	for _, result := range []string{"sent", "retried", "dropped"} {
+		p.requests.WithLabelValues(result)
+	}
+	return p, nil
+}
+
+// Run gathers and exports the metrics until ctx is done
+func (p *Pusher) Run(ctx context.Context) {
+	p.logger.Info("exporting metrics to OTLP endpoint", "url", p.url, "protocol", p.cfg.Protocol, "interval", p.cfg.Interval)
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		next, cancel := context.WithTimeout(ctx, p.cfg.Interval)
+		p.export(next)
+		cancel()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// export gathers the metrics and sends them, retrying until ctx is done
+func (p *Pusher) export(ctx context.Context) {
+	mfs, _ := p.gather(ctx)
+	if len(mfs) == 0 {
+		return
+	}
+	req := p.encoder.encode(mfs, p.now())
+
+	backoff := p.cfg.MinBackoff
+	for {
+		err := p.send(ctx, req)
+		var exportErr *exportError
+		switch {
+		case err == nil:
+			p.requests.WithLabelValues("sent").Inc()
+			p.lastSuccess.Set(float64(p.now().UnixNano()) / 1e9)
+			return
+		case errors.As(err, &exportErr) && !exportErr.retryable:
+			p.logger.Error("OTLP export rejected, dropping it", "error", err)
+			p.requests.WithLabelValues("dropped").Inc()
+			return
+		}
+		p.logger.Warn("OTLP export failed, retrying", "error", err, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			p.logger.Error("OTLP export failed until the next interval, dropping it", "error", err)
+			p.requests.WithLabelValues("dropped").Inc()
+			return
+		case <-time.After(backoff):
+		}
+		p.requests.WithLabelValues("retried").Inc()
+		backoff *= 2
+	}
+}
+
+type exportError struct {
+	msg       string
+	retryable bool
+}
+
+func (e *exportError) Error() string {
+	return e.msg
+}
+
+// retryableCodes are the gRPC status codes to retry, as listed by the OTLP specification
+var retryableCodes = []int{1, 4, 8, 10, 11, 14, 15}
+
+func (p *Pusher) send(ctx context.Context, req []byte) error {
+	body := req
+	if p.cfg.Compression == CompressionGzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(req); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+	if p.cfg.Protocol == ProtocolGRPC {
+		// length-prefixed message: compressed flag and big endian length
+		frame := make([]byte, 5, 5+len(body))
+		if p.cfg.Compression == CompressionGzip {
+			frame[0] = 1
+		}
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(body)))
+		body = append(frame, body...)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("User-Agent", userAgent)
+	if p.cfg.Protocol == ProtocolGRPC {
+		httpReq.Header.Set("Content-Type", "application/grpc")
+		httpReq.Header.Set("TE", "trailers")
+		if p.cfg.Compression == CompressionGzip {
+			httpReq.Header.Set("Grpc-Encoding", "gzip")
+		}
+	} else {
+		httpReq.Header.Set("Content-Type", "application/x-protobuf")
+		if p.cfg.Compression == CompressionGzip {
+			httpReq.Header.Set("Content-Encoding", "gzip")
+		}
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// the body has to be read for the trailers
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if err == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+	}
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if p.cfg.Protocol == ProtocolGRPC && resp.StatusCode == http.StatusOK {
+		status := resp.Trailer.Get("Grpc-Status")
+		msg := resp.Trailer.Get("Grpc-Message")
+		if status == "" {
+			// trailers-only response
+			status, msg = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+		}
+		code, err := strconv.Atoi(status)
+		switch {
+		case err != nil:
+			return &exportError{msg: fmt.Sprintf("invalid gRPC status %q", status), retryable: true}
+		case code != 0:
+			return &exportError{msg: fmt.Sprintf("server returned gRPC status %d: %s", code, msg), retryable: slices.Contains(retryableCodes, code)}
+		}
+		return nil
+	}
+	if resp.StatusCode/100 != 2 {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusBadGateway ||
+			resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout
+		return &exportError{msg: fmt.Sprintf("server returned HTTP status %d: %s", resp.StatusCode, bytes.TrimSpace(respBody)), retryable: retryable}
+	}
+	return nil
+}
+
+// Describe implements prometheus.Collector for the syslogng_exporter_otlp_* metrics
+func (p *Pusher) Describe(ch chan<- *prometheus.Desc) {
+	p.requests.Describe(ch)
+	p.lastSuccess.Describe(ch)
+}
+
+// Collect implements prometheus.Collector for the syslogng_exporter_otlp_* metrics
+func (p *Pusher) Collect(ch chan<- prometheus.Metric) {
+	p.requests.Collect(ch)
+	p.lastSuccess.Collect(ch)
+}
@@ -0,0 +1,350 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/internal/metrictest"
+)
+
+const input = `# HELP syslogng_output_events_total Number of messages delivered, dropped or queued by the destination.
+# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{id="d1",result="delivered"} %d
+# TYPE syslogng_up gauge
+syslogng_up 1
+# TYPE syslogng_delay_seconds histogram
+syslogng_delay_seconds_bucket{le="1"} 2
+syslogng_delay_seconds_sum 1.5
+syslogng_delay_seconds_count 3
+# TYPE syslogng_latency_seconds summary
+syslogng_latency_seconds{quantile="0.5"} 0.25
+syslogng_latency_seconds_sum 2
+syslogng_latency_seconds_count 4
+`
+
+// message is a decoded protobuf message: the raw values of the fields by field number
+type message struct {
+	t      *testing.T
+	fields map[protowire.Number][][]byte
+}
+
+func decode(t *testing.T, b []byte) message {
+	m := message{t: t, fields: make(map[protowire.Number][][]byte)}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		require.GreaterOrEqual(t, n, 0)
+		value := b[:n]
+		if typ == protowire.BytesType {
+			value, _ = protowire.ConsumeBytes(value)
+		}
+		m.fields[num] = append(m.fields[num], value)
+		b = b[n:]
+	}
+	return m
+}
+
+func (m message) messages(num protowire.Number) []message {
+	var msgs []message
+	for _, b := range m.fields[num] {
+		msgs = append(msgs, decode(m.t, b))
+	}
+	return msgs
+}
+
+func (m message) string(num protowire.Number) string {
+	if len(m.fields[num]) == 0 {
+		return ""
+	}
+	return string(m.fields[num][0])
+}
+
+func (m message) fixed64(num protowire.Number) uint64 {
+	if len(m.fields[num]) == 0 {
+		return 0
+	}
+	v, _ := protowire.ConsumeFixed64(m.fields[num][0])
+	return v
+}
+
+func (m message) varint(num protowire.Number) uint64 {
+	if len(m.fields[num]) == 0 {
+		return 0
+	}
+	v, _ := protowire.ConsumeVarint(m.fields[num][0])
+	return v
+}
+
+func (m message) packedFixed64(num protowire.Number) []uint64 {
+	var vs []uint64
+	for _, b := range m.fields[num] {
+		for len(b) > 0 {
+			v, n := protowire.ConsumeFixed64(b)
+			vs = append(vs, v)
+			b = b[n:]
+		}
+	}
+	return vs
+}
+
+func (m message) attributes(num protowire.Number) string {
+	var attrs []string
+	for _, kv := range m.messages(num) {
+		attrs = append(attrs, kv.string(keyValueKey)+"="+kv.messages(keyValueValue)[0].string(anyValueString))
+	}
+	return "{" + strings.Join(attrs, ",") + "}"
+}
+
+// decodeRequest decodes an ExportMetricsServiceRequest as the line of the resource and lines of the data points
+func decodeRequest(t *testing.T, req []byte) []string {
+	rms := decode(t, req).messages(requestResourceMetrics)
+	require.Len(t, rms, 1)
+	lines := []string{"resource " + rms[0].messages(resourceMetricsResource)[0].attributes(resourceAttributes)}
+	sms := rms[0].messages(resourceMetricsScope)
+	require.Len(t, sms, 1)
+	scope := sms[0].messages(scopeMetricsScope)[0]
+	lines = append(lines, "scope "+scope.string(scopeName)+" "+scope.string(scopeVersion))
+
+	seconds := func(ns uint64) string {
+		return fmt.Sprint(time.Unix(0, int64(ns)).Unix())
+	}
+	for _, metric := range sms[0].messages(scopeMetricsMetrics) {
+		name := metric.string(metricName) + "[" + metric.string(metricUnit) + "]"
+		switch {
+		case metric.fields[metricSum] != nil:
+			sum := metric.messages(metricSum)[0]
+			for _, p := range sum.messages(dataPoints) {
+				lines = append(lines, fmt.Sprintf("sum temporality=%d monotonic=%d %s%s %g start=%s time=%s",
+					sum.varint(aggregationTemporality), sum.varint(sumIsMonotonic), name, p.attributes(numberPointAttributes),
+					math.Float64frombits(p.fixed64(numberPointDouble)), seconds(p.fixed64(numberPointStartTime)), seconds(p.fixed64(numberPointTime))))
+			}
+		case metric.fields[metricGauge] != nil:
+			for _, p := range metric.messages(metricGauge)[0].messages(dataPoints) {
+				lines = append(lines, fmt.Sprintf("gauge %s%s %g time=%s", name, p.attributes(numberPointAttributes),
+					math.Float64frombits(p.fixed64(numberPointDouble)), seconds(p.fixed64(numberPointTime))))
+			}
+		case metric.fields[metricHistogram] != nil:
+			h := metric.messages(metricHistogram)[0]
+			for _, p := range h.messages(dataPoints) {
+				var bounds []float64
+				for _, b := range p.packedFixed64(histogramPointBounds) {
+					bounds = append(bounds, math.Float64frombits(b))
+				}
+				lines = append(lines, fmt.Sprintf("histogram temporality=%d %s%s count=%d sum=%g buckets=%v bounds=%v start=%s",
+					h.varint(aggregationTemporality), name, p.attributes(histogramPointAttributes), p.fixed64(histogramPointCount),
+					math.Float64frombits(p.fixed64(histogramPointSum)), p.packedFixed64(histogramPointBuckets), bounds,
+					seconds(p.fixed64(histogramPointStartTime))))
+			}
+		case metric.fields[metricSummary] != nil:
+			for _, p := range metric.messages(metricSummary)[0].messages(dataPoints) {
+				var quantiles []string
+				for _, q := range p.messages(summaryPointQuantiles) {
+					quantiles = append(quantiles, fmt.Sprintf("%g:%g", math.Float64frombits(q.fixed64(quantileQuantile)), math.Float64frombits(q.fixed64(quantileValue))))
+				}
+				lines = append(lines, fmt.Sprintf("summary %s%s count=%d sum=%g quantiles=%v start=%s", name, p.attributes(summaryPointAttributes),
+					p.fixed64(summaryPointCount), math.Float64frombits(p.fixed64(summaryPointSum)), quantiles, seconds(p.fixed64(summaryPointStartTime))))
+			}
+		}
+	}
+	return lines
+}
+
+func TestEncode(t *testing.T) {
+	e := newEncoder(map[string]string{"service.name": "syslog-ng", "host.name": "h1"}, "v1")
+
+	mfs := metrictest.Parse(t, fmt.Sprintf(input, 10))
+	mfs[2].Unit = new("seconds")
+	assert.Equal(t, []string{
+		`resource {host.name=h1,service.name=syslog-ng}`,
+		`scope github.com/axoflow/axosyslog-metrics-exporter v1`,
+		`sum temporality=2 monotonic=1 syslogng_output_events_total[]{id=d1,result=delivered} 10 start=100 time=100`,
+		`gauge syslogng_up[]{} 1 time=100`,
+		`histogram temporality=2 syslogng_delay_seconds[s]{} count=3 sum=1.5 buckets=[2 1] bounds=[1] start=100`,
+		`summary syslogng_latency_seconds[]{} count=4 sum=2 quantiles=[0.5:0.25] start=100`,
+	}, decodeRequest(t, e.encode(mfs, time.Unix(100, 0))))
+
+	// the start time is kept while the counter grows
+	lines := decodeRequest(t, e.encode(metrictest.Parse(t, fmt.Sprintf(input, 20)), time.Unix(130, 0)))
+	assert.Equal(t, `sum temporality=2 monotonic=1 syslogng_output_events_total[]{id=d1,result=delivered} 20 start=100 time=130`, lines[2])
+
+	// after a reset (e.g. syslog-ng restarted), the counter started after the previous observation
+	lines = decodeRequest(t, e.encode(metrictest.Parse(t, fmt.Sprintf(input, 5)), time.Unix(160, 0)))
+	assert.Equal(t, `sum temporality=2 monotonic=1 syslogng_output_events_total[]{id=d1,result=delivered} 5 start=130 time=160`, lines[2])
+
+	// the created timestamp is the start time when present
+	mfs = metrictest.Parse(t, fmt.Sprintf(input, 30))
+	mfs[0].Metric[0].Counter.CreatedTimestamp = timestamppb.New(time.Unix(50, 0))
+	lines = decodeRequest(t, e.encode(mfs, time.Unix(190, 0)))
+	assert.Equal(t, `sum temporality=2 monotonic=1 syslogng_output_events_total[]{id=d1,result=delivered} 30 start=50 time=190`, lines[2])
+}
+
+// collector is a stand-in for the OTLP receivers of the OpenTelemetry collector
+type collector struct {
+	t        *testing.T
+	grpc     bool
+	mu       sync.Mutex
+	statuses []int
+	received [][]string
+	headers  http.Header
+	path     string
+	proto    int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.headers = r.Header.Clone()
+	c.path = r.URL.Path
+	c.proto = r.ProtoMajor
+	var status int
+	if len(c.statuses) > 0 {
+		status, c.statuses = c.statuses[0], c.statuses[1:]
+	}
+
+	body, err := io.ReadAll(r.Body)
+	require.NoError(c.t, err)
+	compressed := r.Header.Get("Content-Encoding") == "gzip"
+	if c.grpc {
+		require.GreaterOrEqual(c.t, len(body), 5)
+		require.Equal(c.t, int(binary.BigEndian.Uint32(body[1:5])), len(body)-5)
+		compressed = body[0] == 1
+		body = body[5:]
+	}
+	if compressed {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(c.t, err)
+		body, err = io.ReadAll(zr)
+		require.NoError(c.t, err)
+	}
+
+	if c.grpc {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.WriteHeader(http.StatusOK)
+		if status == 0 {
+			c.received = append(c.received, decodeRequest(c.t, body))
+			// empty ExportMetricsServiceResponse
+			w.Write([]byte{0, 0, 0, 0, 0})
+		}
+		w.Header().Set("Grpc-Status", fmt.Sprint(status))
+		w.Header().Set("Grpc-Message", "status")
+		return
+	}
+	if status == 0 {
+		status = http.StatusOK
+		c.received = append(c.received, decodeRequest(c.t, body))
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(status)
+}
+
+func TestPusher(t *testing.T) {
+	for name, tc := range map[string]struct {
+		protocol string
+		statuses []int
+		path     string
+	}{
+		// 503 is retried, 400 is dropped
+		"http": {protocol: ProtocolHTTP, statuses: []int{http.StatusServiceUnavailable, http.StatusBadRequest}, path: "/v1/metrics"},
+		// UNAVAILABLE is retried, INVALID_ARGUMENT is dropped
+		"grpc": {protocol: ProtocolGRPC, statuses: []int{14, 3}, path: "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := &collector{t: t, grpc: tc.protocol == ProtocolGRPC, statuses: tc.statuses}
+			srv := httptest.NewUnstartedServer(c)
+			srv.Config.Protocols = new(http.Protocols)
+			srv.Config.Protocols.SetHTTP1(true)
+			srv.Config.Protocols.SetUnencryptedHTTP2(true)
+			srv.Start()
+			defer srv.Close()
+
+			pusher, err := New(Config{
+				Endpoint:           srv.URL,
+				Protocol:           tc.protocol,
+				Interval:           50 * time.Millisecond,
+				MinBackoff:         time.Millisecond,
+				ResourceAttributes: map[string]string{"service.name": "syslog-ng"},
+			}, func(context.Context) ([]*io_prometheus_client.MetricFamily, error) {
+				return metrictest.Parse(t, fmt.Sprintf(input, 1))[1:2], nil
+			}, WithLogger(slog.New(slog.DiscardHandler)), WithVersion("v1"))
+			require.NoError(t, err)
+			pusher.now = func() time.Time { return time.Unix(100, 0) }
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				pusher.Run(ctx)
+				close(done)
+			}()
+			require.Eventually(t, func() bool { return testutil.ToFloat64(pusher.requests.WithLabelValues("sent")) >= 1 }, 5*time.Second, time.Millisecond)
+			cancel()
+			<-done
+
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			assert.Equal(t, tc.path, c.path)
+			assert.Equal(t, []string{
+				`resource {service.name=syslog-ng}`,
+				`scope github.com/axoflow/axosyslog-metrics-exporter v1`,
+				`gauge syslogng_up[]{} 1 time=100`,
+			}, c.received[0])
+			if tc.protocol == ProtocolGRPC {
+				assert.Equal(t, 2, c.proto)
+				assert.Equal(t, "application/grpc", c.headers.Get("Content-Type"))
+				assert.Equal(t, "gzip", c.headers.Get("Grpc-Encoding"))
+			} else {
+				assert.Equal(t, "application/x-protobuf", c.headers.Get("Content-Type"))
+				assert.Equal(t, "gzip", c.headers.Get("Content-Encoding"))
+			}
+			assert.Equal(t, 1.0, testutil.ToFloat64(pusher.requests.WithLabelValues("retried")))
+			assert.Equal(t, 1.0, testutil.ToFloat64(pusher.requests.WithLabelValues("dropped")))
+		})
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	gather := func(context.Context) ([]*io_prometheus_client.MetricFamily, error) { return nil, nil }
+	for _, cfg := range []Config{
+		{Endpoint: "collector:4317"},
+		{Endpoint: "http://collector:4318", Protocol: "http/json"},
+		{Endpoint: "http://collector:4318", Compression: "zstd"},
+	} {
+		_, err := New(cfg, gather)
+		assert.Error(t, err, cfg)
+	}
+}
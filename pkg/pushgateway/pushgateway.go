@@ -0,0 +1,196 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pushgateway pushes the metrics to a Prometheus Pushgateway, for short-lived syslog-ng instances (e.g. batch
+// jobs) that finish before they are scraped.
+package pushgateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/exporter"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/httpclient"
+)
+
+const (
+	DefaultJob      = "axosyslog"
+	DefaultInterval = 30 * time.Second
+	DefaultTimeout  = 10 * time.Second
+)
+
+// Config is the configuration of the Pushgateway as it appears in the configuration file
+type Config struct {
+	URL string `yaml:"url"`
+	// Job is the job label of the pushed group (default: DefaultJob)
+	Job string `yaml:"job"`
+	// Grouping are the labels of the grouping key besides job, e.g. instance
+	Grouping map[string]string `yaml:"grouping"`
+	// Interval is the time between the periodic pushes (default: DefaultInterval)
+	Interval time.Duration `yaml:"interval"`
+	// Timeout is the timeout of a request (default: DefaultTimeout)
+	Timeout time.Duration `yaml:"timeout"`
+	// DeleteOnShutdown deletes the group after the final push on shutdown, so that the metrics of finished jobs do
+	// not linger on the Pushgateway
+	DeleteOnShutdown bool              `yaml:"delete_on_shutdown"`
+	HTTP             httpclient.Config `yaml:",inline"`
+}
+
+// Pusher pushes the metrics to the Pushgateway at the interval, replacing the metrics of the group, and once more
+// when it is stopped
+type Pusher struct {
+	cfg    Config
+	client *http.Client
+	gather exporter.GatherFunc
+	logger *slog.Logger
+	now    func() time.Time
+
+	pushes      *prometheus.CounterVec
+	lastSuccess prometheus.Gauge
+}
+
+// Option is an option for New
+type Option func(*Pusher)
+
+// WithLogger sets the logger of the pusher (default: slog.Default)
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Pusher) {
+		p.logger = logger
+	}
+}
+
+func New(cfg Config, gather exporter.GatherFunc, opts ...Option) (*Pusher, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid Pushgateway URL %q", cfg.URL)
+	}
+	if cfg.Job == "" {
+		cfg.Job = DefaultJob
+	}
+	for name := range cfg.Grouping {
+		if name == "job" || !model.LabelName(name).IsValidLegacy() {
+			return nil, fmt.Errorf("invalid grouping label %q", name)
+		}
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	p := &Pusher{
+		cfg:    cfg,
+		gather: gather,
+		logger: slog.Default(),
+		now:    time.Now,
+		pushes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "syslogng_exporter_pushgateway_pushes_total",
+			Help: "Number of pushes to the Pushgateway by result: sent or failed.",
+		}, []string{"result"}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "syslogng_exporter_pushgateway_last_success_timestamp_seconds",
+			Help: "Time of the last successful push to the Pushgateway, in Unix time.",
+		}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.client, err = cfg.HTTP.NewClient(cfg.Timeout); err != nil {
+		return nil, err
+	}
+	for _, result := range []string{"sent", "failed"} {
+		p.pushes.WithLabelValues(result)
+	}
+	return p, nil
+}
+
+// Run pushes the metrics at the interval until ctx is done, then pushes them once more, and deletes the group if
+// configured. The final push is done with a fresh context, so that it is not canceled along with ctx.
+func (p *Pusher) Run(ctx context.Context) {
+	p.logger.Info("pushing metrics to Pushgateway", "url", p.cfg.URL, "job", p.cfg.Job, "grouping", p.cfg.Grouping, "interval", p.cfg.Interval)
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		p.push(ctx)
+		select {
+		case <-ctx.Done():
+			p.shutdown()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// shutdown does the final push, and deletes the group if configured
+func (p *Pusher) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*p.cfg.Timeout)
+	defer cancel()
+	if !p.push(ctx) {
+		return
+	}
+	p.logger.Info("final push to Pushgateway done", "job", p.cfg.Job, "grouping", p.cfg.Grouping)
+	if p.cfg.DeleteOnShutdown {
+		if err := p.pusher(ctx).Delete(); err != nil {
+			p.logger.Error("deleting group from Pushgateway failed", "error", err)
+			return
+		}
+		p.logger.Info("group deleted from Pushgateway", "job", p.cfg.Job, "grouping", p.cfg.Grouping)
+	}
+}
+
+// push gathers the metrics and replaces the metrics of the group with them, and reports whether it succeeded
+func (p *Pusher) push(ctx context.Context) bool {
+	if err := p.pusher(ctx).PushContext(ctx); err != nil {
+		p.logger.Warn("pushing metrics to Pushgateway failed", "error", err)
+		p.pushes.WithLabelValues("failed").Inc()
+		return false
+	}
+	p.pushes.WithLabelValues("sent").Inc()
+	p.lastSuccess.Set(float64(p.now().UnixNano()) / 1e9)
+	return true
+}
+
+func (p *Pusher) pusher(ctx context.Context) *push.Pusher {
+	pusher := push.New(p.cfg.URL, p.cfg.Job).Client(p.client).Gatherer(prometheus.GathererFunc(func() ([]*io_prometheus_client.MetricFamily, error) {
+		mfs, _ := p.gather(ctx)
+		// the Pushgateway rejects samples with timestamps
+		return exporter.WithoutTimestamps(mfs), nil
+	}))
+	for name, value := range p.cfg.Grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+	return pusher
+}
+
+// Describe implements prometheus.Collector for the syslogng_exporter_pushgateway_* metrics
+func (p *Pusher) Describe(ch chan<- *prometheus.Desc) {
+	p.pushes.Describe(ch)
+	p.lastSuccess.Describe(ch)
+}
+
+// Collect implements prometheus.Collector for the syslogng_exporter_pushgateway_* metrics
+func (p *Pusher) Collect(ch chan<- prometheus.Metric) {
+	p.pushes.Collect(ch)
+	p.lastSuccess.Collect(ch)
+}
@@ -0,0 +1,126 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pushgateway
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pushgateway is a stand-in for the Pushgateway that records the requests
+type pushgateway struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   []map[string]*io_prometheus_client.MetricFamily
+}
+
+func (pg *pushgateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	pg.requests = append(pg.requests, r.Method+" "+r.URL.Path)
+	if r.Method == http.MethodDelete {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	mfs := make(map[string]*io_prometheus_client.MetricFamily)
+	dec := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+	for {
+		var mf io_prometheus_client.MetricFamily
+		if err := dec.Decode(&mf); err != nil {
+			if err != io.EOF {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			break
+		}
+		mfs[mf.GetName()] = &mf
+	}
+	pg.bodies = append(pg.bodies, mfs)
+	w.WriteHeader(http.StatusOK)
+}
+
+func gather(context.Context) ([]*io_prometheus_client.MetricFamily, error) {
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	mfs, err := parser.TextToMetricFamilies(strings.NewReader(`# TYPE syslogng_up gauge
+syslogng_up 1
+# TYPE syslogng_output_event_delay_sample_seconds gauge
+syslogng_output_event_delay_sample_seconds{id="d1"} 0.5 1700000000000
+`))
+	return []*io_prometheus_client.MetricFamily{mfs["syslogng_up"], mfs["syslogng_output_event_delay_sample_seconds"]}, err
+}
+
+func TestPusher(t *testing.T) {
+	pg := &pushgateway{}
+	srv := httptest.NewServer(pg)
+	defer srv.Close()
+
+	p, err := New(Config{
+		URL:              srv.URL,
+		Grouping:         map[string]string{"instance": "batch-42"},
+		Interval:         time.Hour,
+		DeleteOnShutdown: true,
+	}, gather, WithLogger(slog.New(slog.DiscardHandler)))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		pg.mu.Lock()
+		defer pg.mu.Unlock()
+		return len(pg.requests) >= 1
+	}, 5*time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	// the periodic push, the final push and the deletion of the group
+	assert.Equal(t, []string{
+		"PUT /metrics/job/axosyslog/instance/batch-42",
+		"PUT /metrics/job/axosyslog/instance/batch-42",
+		"DELETE /metrics/job/axosyslog/instance/batch-42",
+	}, pg.requests)
+	require.Len(t, pg.bodies, 2)
+	assert.Equal(t, 1.0, pg.bodies[1]["syslogng_up"].GetMetric()[0].GetGauge().GetValue())
+	assert.Nil(t, pg.bodies[1]["syslogng_output_event_delay_sample_seconds"].GetMetric()[0].TimestampMs, "timestamps are left out")
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, cfg := range []Config{
+		{URL: "pushgateway:9091"},
+		{URL: "http://pushgateway:9091", Grouping: map[string]string{"job": "other"}},
+		{URL: "http://pushgateway:9091", Grouping: map[string]string{"not-a-label": "x"}},
+	} {
+		_, err := New(cfg, gather)
+		assert.Error(t, err, cfg)
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package relabel rewrites the labels of metric families, modeled on the metric_relabel_configs of Prometheus.
+//
+// Reference: https://prometheus.io/docs/prometheus/latest/configuration/configuration/#relabel_config
+package relabel
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+)
+
+// MetricNameLabel is the label holding the name of the metric family during relabeling
+const MetricNameLabel = "__name__"
+
+type Action string
+
+const (
+	Replace   Action = "replace"
+	Keep      Action = "keep"
+	Drop      Action = "drop"
+	LabelDrop Action = "labeldrop"
+	LabelKeep Action = "labelkeep"
+	LabelMap  Action = "labelmap"
+	HashMod   Action = "hashmod"
+)
+
+// Config is a relabeling rule as it appears in the configuration file
+type Config struct {
+	SourceLabels []string `yaml:"source_labels"`
+	Separator    *string  `yaml:"separator"`
+	Regex        *string  `yaml:"regex"`
+	Modulus      uint64   `yaml:"modulus"`
+	TargetLabel  string   `yaml:"target_label"`
+	Replacement  *string  `yaml:"replacement"`
+	Action       Action   `yaml:"action"`
+}
+
+// Rule is a compiled relabeling rule
+type Rule struct {
+	sourceLabels []string
+	separator    string
+	regex        *regexp.Regexp
+	modulus      uint64
+	targetLabel  string
+	replacement  string
+	action       Action
+}
+
+// Compile validates the rule and sets the defaults of Prometheus for the missing fields
+func (c Config) Compile() (*Rule, error) {
+	r := &Rule{
+		sourceLabels: c.SourceLabels,
+		separator:    ";",
+		modulus:      c.Modulus,
+		targetLabel:  c.TargetLabel,
+		replacement:  "$1",
+		action:       c.Action,
+	}
+	if c.Separator != nil {
+		r.separator = *c.Separator
+	}
+	if c.Replacement != nil {
+		r.replacement = *c.Replacement
+	}
+	if r.action == "" {
+		r.action = Replace
+	}
+
+	regex := "(.*)"
+	if c.Regex != nil {
+		regex = *c.Regex
+	}
+	var err error
+	if r.regex, err = regexp.Compile("^(?:" + regex + ")$"); err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %w", regex, err)
+	}
+
+	switch r.action {
+	case Replace:
+		if r.targetLabel == "" {
+			return nil, fmt.Errorf("relabel action %q requires target_label", r.action)
+		}
+	case HashMod:
+		if r.targetLabel == "" {
+			return nil, fmt.Errorf("relabel action %q requires target_label", r.action)
+		}
+		if r.modulus == 0 {
+			return nil, fmt.Errorf("relabel action %q requires a non-zero modulus", r.action)
+		}
+	case Keep, Drop:
+		if len(r.sourceLabels) == 0 {
+			return nil, fmt.Errorf("relabel action %q requires source_labels", r.action)
+		}
+	case LabelDrop, LabelKeep, LabelMap:
+	default:
+		return nil, fmt.Errorf("unknown relabel action %q", r.action)
+	}
+	return r, nil
+}
+
+// Rules are relabeling rules applied in order
+type Rules []*Rule
+
+// Compile compiles the relabeling rules of the configuration
+func Compile(configs []Config) (Rules, error) {
+	rules := make(Rules, 0, len(configs))
+	for i, c := range configs {
+		r, err := c.Compile()
+		if err != nil {
+			return nil, fmt.Errorf("relabel rule #%d: %w", i+1, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Transform relabels every series of the metric families. Series that are dropped are removed, and series whose
+// name is changed are moved to the metric family of the new name. Labels starting with __ are removed afterwards.
+func (rules Rules) Transform(mfs []*io_prometheus_client.MetricFamily) []*io_prometheus_client.MetricFamily {
+	if len(rules) == 0 {
+		return mfs
+	}
+
+	var res []*io_prometheus_client.MetricFamily
+	byName := make(map[string]*io_prometheus_client.MetricFamily)
+	for _, mf := range mfs {
+		for _, m := range mf.Metric {
+			ls := newLabels(mf.GetName(), m.Label)
+			if !rules.apply(ls) {
+				continue
+			}
+			name := ls.get(MetricNameLabel)
+			if name == "" {
+				continue
+			}
+			target, ok := byName[name]
+			if !ok {
+				target = &io_prometheus_client.MetricFamily{
+					Name: new(name),
+					Help: mf.Help,
+					Type: mf.Type,
+					Unit: unitOf(name, mf.Unit),
+				}
+				byName[name] = target
+				res = append(res, target)
+			}
+			if target.GetType() != mf.GetType() {
+				continue // cannot be merged into a family of another type
+			}
+			m.Label = ls.labelPairs()
+			target.Metric = append(target.Metric, m)
+		}
+	}
+
+	return slices.DeleteFunc(res, func(mf *io_prometheus_client.MetricFamily) bool {
+		return len(mf.Metric) == 0
+	})
+}
+
+// apply relabels the label set and reports whether it is kept
+func (rules Rules) apply(ls *labels) bool {
+	for _, r := range rules {
+		if !r.apply(ls) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Rule) apply(ls *labels) bool {
+	values := make([]string, 0, len(r.sourceLabels))
+	for _, name := range r.sourceLabels {
+		values = append(values, ls.get(name))
+	}
+	value := strings.Join(values, r.separator)
+
+	switch r.action {
+	case Keep:
+		return r.regex.MatchString(value)
+	case Drop:
+		return !r.regex.MatchString(value)
+	case Replace:
+		match := r.regex.FindStringSubmatchIndex(value)
+		if match == nil {
+			return true
+		}
+		target := string(r.regex.ExpandString(nil, r.targetLabel, value, match))
+		if !isValidLabelName(target) {
+			return true
+		}
+		ls.set(target, string(r.regex.ExpandString(nil, r.replacement, value, match)))
+	case HashMod:
+		sum := md5.Sum([]byte(value))
+		ls.set(r.targetLabel, strconv.FormatUint(binary.BigEndian.Uint64(sum[8:])%r.modulus, 10))
+	case LabelMap:
+		for _, l := range slices.Clone(ls.pairs) {
+			if match := r.regex.FindStringSubmatchIndex(l.name); match != nil {
+				ls.set(string(r.regex.ExpandString(nil, r.replacement, l.name, match)), l.value)
+			}
+		}
+	case LabelDrop:
+		ls.pairs = slices.DeleteFunc(ls.pairs, func(l label) bool {
+			return l.name != MetricNameLabel && r.regex.MatchString(l.name)
+		})
+	case LabelKeep:
+		ls.pairs = slices.DeleteFunc(ls.pairs, func(l label) bool {
+			return l.name != MetricNameLabel && !r.regex.MatchString(l.name)
+		})
+	}
+	return true
+}
+
+func isValidLabelName(name string) bool {
+	return model.LabelName(name).IsValidLegacy()
+}
+
+// unitOf returns the unit of a metric family renamed to name, the unit is dropped if it is not the suffix of the new
+// name as required by OpenMetrics
+func unitOf(name string, unit *string) *string {
+	if unit == nil || strings.HasSuffix(strings.TrimSuffix(name, "_total"), "_"+*unit) {
+		return unit
+	}
+	return nil
+}
+
+type label struct {
+	name, value string
+}
+
+// labels is the label set of a series during relabeling, including the __name__ label
+type labels struct {
+	pairs []label
+}
+
+func newLabels(name string, pairs []*io_prometheus_client.LabelPair) *labels {
+	ls := &labels{pairs: make([]label, 0, len(pairs)+1)}
+	ls.pairs = append(ls.pairs, label{name: MetricNameLabel, value: name})
+	for _, p := range pairs {
+		ls.pairs = append(ls.pairs, label{name: p.GetName(), value: p.GetValue()})
+	}
+	return ls
+}
+
+func (ls *labels) get(name string) string {
+	for _, l := range ls.pairs {
+		if l.name == name {
+			return l.value
+		}
+	}
+	return ""
+}
+
+// set sets the value of a label, an empty value removes the label
+func (ls *labels) set(name string, value string) {
+	i := slices.IndexFunc(ls.pairs, func(l label) bool { return l.name == name })
+	switch {
+	case i < 0 && value != "":
+		ls.pairs = append(ls.pairs, label{name: name, value: value})
+	case i >= 0 && value != "":
+		ls.pairs[i].value = value
+	case i >= 0:
+		ls.pairs = slices.Delete(ls.pairs, i, i+1)
+	}
+}
+
+// labelPairs returns the labels of the series ordered by name, without the ones starting with __
+func (ls *labels) labelPairs() []*io_prometheus_client.LabelPair {
+	slices.SortFunc(ls.pairs, func(a, b label) int {
+		return strings.Compare(a.name, b.name)
+	})
+	res := make([]*io_prometheus_client.LabelPair, 0, len(ls.pairs))
+	for _, l := range ls.pairs {
+		if strings.HasPrefix(l.name, "__") {
+			continue
+		}
+		res = append(res, &io_prometheus_client.LabelPair{Name: new(l.name), Value: new(l.value)})
+	}
+	return res
+}
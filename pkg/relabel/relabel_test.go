@@ -0,0 +1,180 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relabel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/internal/metrictest"
+)
+
+const input = `# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{id="d_elastic#0",driver_instance="http,https://user:pass@es:9200",result="delivered"} 10
+syslogng_output_events_total{id="d_elastic#0",driver_instance="http,https://user:pass@es:9200",result="dropped"} 1
+syslogng_output_events_total{id="d_file#0",driver_instance="/var/log/messages",result="delivered"} 5
+# TYPE syslogng_input_events_total counter
+syslogng_input_events_total{id="s_net#0",result="processed"} 16
+# TYPE syslogng_memory_queue_events gauge
+syslogng_memory_queue_events{id="d_elastic#0"} 2
+`
+
+func TestRulesTransform(t *testing.T) {
+	testCases := map[string]struct {
+		rules    string
+		expected string
+	}{
+		"drop family": {
+			rules: `
+- source_labels: [__name__]
+  regex: syslogng_memory_queue_.*
+  action: drop`,
+			expected: `syslogng_input_events_total{id="s_net#0",result="processed"} 16
+syslogng_output_events_total{driver_instance="http,https://user:pass@es:9200",id="d_elastic#0",result="delivered"} 10
+syslogng_output_events_total{driver_instance="http,https://user:pass@es:9200",id="d_elastic#0",result="dropped"} 1
+syslogng_output_events_total{driver_instance="/var/log/messages",id="d_file#0",result="delivered"} 5
+`,
+		},
+		"keep by multiple labels": {
+			rules: `
+- source_labels: [__name__, result]
+  separator: "/"
+  regex: syslogng_output_events_total/delivered
+  action: keep`,
+			expected: `syslogng_output_events_total{driver_instance="http,https://user:pass@es:9200",id="d_elastic#0",result="delivered"} 10
+syslogng_output_events_total{driver_instance="/var/log/messages",id="d_file#0",result="delivered"} 5
+`,
+		},
+		"replace and labeldrop": {
+			rules: `
+- source_labels: [id]
+  regex: "(.*)#\\d+"
+  target_label: destination
+- action: labeldrop
+  regex: id|driver_instance`,
+			expected: `syslogng_input_events_total{destination="s_net",result="processed"} 16
+syslogng_memory_queue_events{destination="d_elastic"} 2
+syslogng_output_events_total{destination="d_elastic",result="delivered"} 10
+syslogng_output_events_total{destination="d_elastic",result="dropped"} 1
+syslogng_output_events_total{destination="d_file",result="delivered"} 5
+`,
+		},
+		"labelmap and hashmod": {
+			rules: `
+- source_labels: [__name__]
+  regex: syslogng_output_events_total
+  action: keep
+- regex: "(id|result)"
+  replacement: "syslog_${1}"
+  action: labelmap
+- action: labelkeep
+  regex: syslog_.*
+- source_labels: [syslog_id]
+  modulus: 4
+  target_label: shard
+  action: hashmod`,
+			expected: `syslogng_output_events_total{shard="1",syslog_id="d_file#0",syslog_result="delivered"} 5
+syslogng_output_events_total{shard="3",syslog_id="d_elastic#0",syslog_result="delivered"} 10
+syslogng_output_events_total{shard="3",syslog_id="d_elastic#0",syslog_result="dropped"} 1
+`,
+		},
+		"rename into existing family": {
+			rules: `
+- source_labels: [__name__]
+  regex: syslogng_input_events_total
+  target_label: __name__
+  replacement: syslogng_output_events_total`,
+			expected: `syslogng_memory_queue_events{id="d_elastic#0"} 2
+syslogng_output_events_total{driver_instance="http,https://user:pass@es:9200",id="d_elastic#0",result="delivered"} 10
+syslogng_output_events_total{driver_instance="http,https://user:pass@es:9200",id="d_elastic#0",result="dropped"} 1
+syslogng_output_events_total{driver_instance="/var/log/messages",id="d_file#0",result="delivered"} 5
+syslogng_output_events_total{id="s_net#0",result="processed"} 16
+`,
+		},
+		"invalid target label": {
+			rules: `
+- source_labels: [id]
+  regex: "d_(.*)#0"
+  target_label: "${1}-destination"
+  replacement: "yes"
+  action: replace
+- source_labels: [__name__]
+  regex: syslogng_output_events_total
+  action: keep`,
+			expected: `syslogng_output_events_total{driver_instance="http,https://user:pass@es:9200",id="d_elastic#0",result="delivered"} 10
+syslogng_output_events_total{driver_instance="http,https://user:pass@es:9200",id="d_elastic#0",result="dropped"} 1
+syslogng_output_events_total{driver_instance="/var/log/messages",id="d_file#0",result="delivered"} 5
+`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var configs []Config
+			require.NoError(t, yaml.Unmarshal([]byte(tc.rules), &configs))
+			rules, err := Compile(configs)
+			require.NoError(t, err)
+
+			assert.Equal(t, metrictest.SortLines(tc.expected), metrictest.Samples(rules.Transform(metrictest.Parse(t, input))))
+		})
+	}
+}
+
+func TestRulesTransformRenameUnit(t *testing.T) {
+	var configs []Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+- source_labels: [__name__]
+  regex: syslogng_(.*)_events(_total)?
+  target_label: __name__
+  replacement: syslogng_${1}_messages${2}
+- source_labels: [__name__]
+  regex: syslogng_memory_queue_messages
+  target_label: __name__
+  replacement: syslogng_memory_queue_events`), &configs))
+	rules, err := Compile(configs)
+	require.NoError(t, err)
+
+	mfs := metrictest.Parse(t, input)
+	for _, mf := range mfs {
+		mf.Unit = new("events")
+	}
+	units := make(map[string]string)
+	for _, mf := range rules.Transform(mfs) {
+		units[mf.GetName()] = mf.GetUnit()
+	}
+	assert.Equal(t, map[string]string{
+		"syslogng_output_messages_total": "",
+		"syslogng_input_messages_total":  "",
+		"syslogng_memory_queue_events":   "events",
+	}, units, "the unit is kept only if it is still the suffix of the name")
+}
+
+func TestCompileErrors(t *testing.T) {
+	for name, c := range map[string]Config{
+		"unknown action":      {Action: "explode"},
+		"replace w/o target":  {Action: Replace},
+		"hashmod w/o modulus": {Action: HashMod, TargetLabel: "shard"},
+		"keep w/o source":     {Action: Keep},
+		"invalid regex":       {Action: LabelDrop, Regex: new("(")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Compile([]Config{c})
+			assert.Error(t, err)
+		})
+	}
+}
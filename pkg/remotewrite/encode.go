@@ -0,0 +1,163 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remotewrite
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the messages of the remote write 1.0 protocol (prometheus.WriteRequest)
+const (
+	writeRequestTimeseries = 1
+	writeRequestMetadata   = 3
+
+	timeSeriesLabels  = 1
+	timeSeriesSamples = 2
+
+	labelName  = 1
+	labelValue = 2
+
+	sampleValue     = 1
+	sampleTimestamp = 2
+
+	metadataType       = 1
+	metadataFamilyName = 2
+	metadataHelp       = 4
+	metadataUnit       = 5
+)
+
+// metadataTypes maps the metric types to the values of prometheus.MetricMetadata.MetricType
+var metadataTypes = map[io_prometheus_client.MetricType]uint64{
+	io_prometheus_client.MetricType_COUNTER:   1,
+	io_prometheus_client.MetricType_GAUGE:     2,
+	io_prometheus_client.MetricType_HISTOGRAM: 3,
+	io_prometheus_client.MetricType_SUMMARY:   5,
+}
+
+type label struct {
+	name, value string
+}
+
+// encodeWriteRequest encodes the metric families as a remote write 1.0 request. Samples without timestamp get the
+// timestamp in milliseconds. Histograms and summaries are split into their series, like in the text format.
+func encodeWriteRequest(mfs []*io_prometheus_client.MetricFamily, timestampMs int64) (req []byte, series int) {
+	for _, mf := range mfs {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			ts := timestampMs
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+			add := func(name string, value float64, extra ...label) {
+				req = protowire.AppendTag(req, writeRequestTimeseries, protowire.BytesType)
+				req = protowire.AppendBytes(req, encodeTimeSeries(name, m.GetLabel(), extra, value, ts))
+				series++
+			}
+
+			switch mf.GetType() {
+			case io_prometheus_client.MetricType_COUNTER:
+				add(name, m.GetCounter().GetValue())
+			case io_prometheus_client.MetricType_GAUGE:
+				add(name, m.GetGauge().GetValue())
+			case io_prometheus_client.MetricType_UNTYPED:
+				add(name, m.GetUntyped().GetValue())
+			case io_prometheus_client.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				var infSeen bool
+				for _, b := range h.GetBucket() {
+					infSeen = infSeen || math.IsInf(b.GetUpperBound(), 1)
+					add(name+"_bucket", float64(b.GetCumulativeCount()), label{"le", formatFloat(b.GetUpperBound())})
+				}
+				if !infSeen {
+					add(name+"_bucket", float64(h.GetSampleCount()), label{"le", "+Inf"})
+				}
+				add(name+"_sum", h.GetSampleSum())
+				add(name+"_count", float64(h.GetSampleCount()))
+			case io_prometheus_client.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add(name, q.GetValue(), label{"quantile", formatFloat(q.GetQuantile())})
+				}
+				add(name+"_sum", s.GetSampleSum())
+				add(name+"_count", float64(s.GetSampleCount()))
+			}
+		}
+	}
+
+	for _, mf := range mfs {
+		var md []byte
+		md = protowire.AppendTag(md, metadataType, protowire.VarintType)
+		md = protowire.AppendVarint(md, metadataTypes[mf.GetType()])
+		md = protowire.AppendTag(md, metadataFamilyName, protowire.BytesType)
+		md = protowire.AppendString(md, mf.GetName())
+		md = protowire.AppendTag(md, metadataHelp, protowire.BytesType)
+		md = protowire.AppendString(md, mf.GetHelp())
+		if mf.GetUnit() != "" {
+			md = protowire.AppendTag(md, metadataUnit, protowire.BytesType)
+			md = protowire.AppendString(md, mf.GetUnit())
+		}
+		req = protowire.AppendTag(req, writeRequestMetadata, protowire.BytesType)
+		req = protowire.AppendBytes(req, md)
+	}
+	return req, series
+}
+
+func encodeTimeSeries(name string, pairs []*io_prometheus_client.LabelPair, extra []label, value float64, timestampMs int64) []byte {
+	labels := make([]label, 0, len(pairs)+len(extra)+1)
+	labels = append(labels, label{"__name__", name})
+	for _, l := range pairs {
+		labels = append(labels, label{l.GetName(), l.GetValue()})
+	}
+	labels = append(labels, extra...)
+	// the receivers require the labels to be sorted by name
+	slices.SortFunc(labels, func(a, b label) int { return cmp.Compare(a.name, b.name) })
+
+	var ts []byte
+	for _, l := range labels {
+		var lb []byte
+		lb = protowire.AppendTag(lb, labelName, protowire.BytesType)
+		lb = protowire.AppendString(lb, l.name)
+		lb = protowire.AppendTag(lb, labelValue, protowire.BytesType)
+		lb = protowire.AppendString(lb, l.value)
+		ts = protowire.AppendTag(ts, timeSeriesLabels, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, lb)
+	}
+
+	var sample []byte
+	sample = protowire.AppendTag(sample, sampleValue, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+	sample = protowire.AppendTag(sample, sampleTimestamp, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(timestampMs))
+	ts = protowire.AppendTag(ts, timeSeriesSamples, protowire.BytesType)
+	ts = protowire.AppendBytes(ts, sample)
+	return ts
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
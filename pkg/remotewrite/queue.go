@@ -0,0 +1,203 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remotewrite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const diskQueueSuffix = ".req"
+
+// queue is a bounded FIFO queue of compressed write requests. When the queue is full, the oldest requests are
+// dropped to make room for the new ones, even the one being sent.
+type queue interface {
+	// push appends the request and returns the number of dropped requests
+	push(req []byte) (dropped int, err error)
+	// peek returns the sequence number and the content of the oldest request, or false if the queue is empty.
+	// The sequence number is returned even if reading the request failed.
+	peek() (uint64, []byte, bool, error)
+	// pop removes the request with the sequence number, unless it was dropped meanwhile, and reports whether it
+	// removed the request
+	pop(seq uint64) (bool, error)
+	len() int
+}
+
+type memoryQueue struct {
+	mu       sync.Mutex
+	capacity int
+	// next is the sequence number of the next pushed request
+	next uint64
+	seqs []uint64
+	reqs [][]byte
+}
+
+func newMemoryQueue(capacity int) *memoryQueue {
+	return &memoryQueue{capacity: capacity}
+}
+
+func (q *memoryQueue) push(req []byte) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.seqs = append(q.seqs, q.next)
+	q.reqs = append(q.reqs, req)
+	q.next++
+	dropped := max(len(q.reqs)-q.capacity, 0)
+	clear(q.reqs[:dropped])
+	q.seqs = q.seqs[dropped:]
+	q.reqs = q.reqs[dropped:]
+	return dropped, nil
+}
+
+func (q *memoryQueue) peek() (uint64, []byte, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.reqs) == 0 {
+		return 0, nil, false, nil
+	}
+	return q.seqs[0], q.reqs[0], true, nil
+}
+
+func (q *memoryQueue) pop(seq uint64) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// the requests are only removed from the head, so a request not at the head was dropped
+	if len(q.seqs) == 0 || q.seqs[0] != seq {
+		return false, nil
+	}
+	q.reqs[0] = nil
+	q.seqs = q.seqs[1:]
+	q.reqs = q.reqs[1:]
+	return true, nil
+}
+
+func (q *memoryQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.reqs)
+}
+
+// diskQueue stores the requests in files of a directory named by their sequence number, so that the requests
+// that could not be sent survive the restarts of the exporter
+type diskQueue struct {
+	mu       sync.Mutex
+	dir      string
+	capacity int
+	seqs     []uint64
+}
+
+func newDiskQueue(dir string, capacity int) (*diskQueue, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	q := &diskQueue{dir: dir, capacity: capacity}
+	for _, e := range entries {
+		name, found := strings.CutSuffix(e.Name(), diskQueueSuffix)
+		if !found || e.IsDir() {
+			continue
+		}
+		seq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		q.seqs = append(q.seqs, seq)
+	}
+	slices.Sort(q.seqs)
+	if _, err := q.trim(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *diskQueue) path(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, diskQueueSuffix))
+}
+
+func (q *diskQueue) push(req []byte) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var seq uint64
+	if len(q.seqs) > 0 {
+		seq = q.seqs[len(q.seqs)-1] + 1
+	}
+
+	f, err := os.CreateTemp(q.dir, "tmp*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(req); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(f.Name(), q.path(seq)); err != nil {
+		return 0, err
+	}
+	q.seqs = append(q.seqs, seq)
+	return q.trim()
+}
+
+func (q *diskQueue) trim() (int, error) {
+	dropped := max(len(q.seqs)-q.capacity, 0)
+	for _, seq := range q.seqs[:dropped] {
+		if err := os.Remove(q.path(seq)); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+	}
+	q.seqs = q.seqs[dropped:]
+	return dropped, nil
+}
+
+func (q *diskQueue) peek() (uint64, []byte, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.seqs) == 0 {
+		return 0, nil, false, nil
+	}
+	req, err := os.ReadFile(q.path(q.seqs[0]))
+	return q.seqs[0], req, true, err
+}
+
+func (q *diskQueue) pop(seq uint64) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// the requests are only removed from the head, so a request not at the head was dropped
+	if len(q.seqs) == 0 || q.seqs[0] != seq {
+		return false, nil
+	}
+	if err := os.Remove(q.path(seq)); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	q.seqs = q.seqs[1:]
+	return true, nil
+}
+
+func (q *diskQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.seqs)
+}
@@ -0,0 +1,307 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package remotewrite pushes the metrics to a Prometheus remote write endpoint, for syslog-ng instances that can
+// not be scraped (e.g. behind NAT).
+//
+// Reference: https://prometheus.io/docs/specs/prw/remote_write_spec/
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/exporter"
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/httpclient"
+)
+
+const (
+	DefaultInterval      = 30 * time.Second
+	DefaultTimeout       = 10 * time.Second
+	DefaultQueueCapacity = 100
+	DefaultMinBackoff    = time.Second
+	DefaultMaxBackoff    = time.Minute
+
+	userAgent = "axosyslog-metrics-exporter"
+)
+
+// Config is the configuration of the remote write endpoint as it appears in the configuration file
+type Config struct {
+	URL string `yaml:"url"`
+	// Interval is the time between gathering the metrics (default: DefaultInterval)
+	Interval time.Duration `yaml:"interval"`
+	// Timeout is the timeout of a request (default: DefaultTimeout)
+	Timeout time.Duration     `yaml:"timeout"`
+	HTTP    httpclient.Config `yaml:",inline"`
+	Queue   QueueConfig       `yaml:"queue"`
+	// MinBackoff and MaxBackoff bound the time between retrying a failed request, which doubles on every failure
+	MinBackoff time.Duration `yaml:"min_backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+// QueueConfig configures the queue of the requests waiting to be sent
+type QueueConfig struct {
+	// Capacity is the maximum number of queued requests, the oldest ones are dropped beyond it (default: DefaultQueueCapacity)
+	Capacity int `yaml:"capacity"`
+	// Directory stores the queue on disk, so that it survives the restarts of the exporter (default: in memory)
+	Directory string `yaml:"directory"`
+}
+
+// Pusher gathers the metrics at the interval and pushes them to the remote write endpoint. Requests that fail with
+// a recoverable error (network errors, 5xx and 429 responses) are retried with backoff, others are dropped.
+type Pusher struct {
+	cfg    Config
+	client *http.Client
+	gather exporter.GatherFunc
+	queue  queue
+	logger *slog.Logger
+	now    func() time.Time
+	notify chan struct{}
+
+	requests    *prometheus.CounterVec
+	queueLength prometheus.GaugeFunc
+	lastSuccess prometheus.Gauge
+}
+
+// Option is an option for New
+type Option func(*Pusher)
+
+// WithLogger sets the logger of the pusher (default: slog.Default)
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Pusher) {
+		p.logger = logger
+	}
+}
+
+func New(cfg Config, gather exporter.GatherFunc, opts ...Option) (*Pusher, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid remote write URL %q", cfg.URL)
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Queue.Capacity <= 0 {
+		cfg.Queue.Capacity = DefaultQueueCapacity
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = DefaultMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = max(DefaultMaxBackoff, cfg.MinBackoff)
+	}
+
+	p := &Pusher{
+		cfg:    cfg,
+		gather: gather,
+		logger: slog.Default(),
+		now:    time.Now,
+		notify: make(chan struct{}, 1),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "syslogng_exporter_remote_write_requests_total",
+			Help: "Number of remote write requests by result: sent, retried or dropped.",
+		}, []string{"result"}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "syslogng_exporter_remote_write_last_success_timestamp_seconds",
+			Help: "Time of the last successful remote write request, in Unix time.",
+		}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.client, err = cfg.HTTP.NewClient(cfg.Timeout); err != nil {
+		return nil, err
+	}
+	if cfg.Queue.Directory != "" {
+		if p.queue, err = newDiskQueue(cfg.Queue.Directory, cfg.Queue.Capacity); err != nil {
+			return nil, fmt.Errorf("opening queue directory failed: %w", err)
+		}
+	} else {
+		p.queue = newMemoryQueue(cfg.Queue.Capacity)
+	}
+	p.queueLength = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "syslogng_exporter_remote_write_queue_requests",
+		Help: "Number of remote write requests waiting to be sent.",
+	}, func() float64 { return float64(p.queue.len()) })
+	for _, result := range []string{"sent", "retried", "dropped"} {
+		p.requests.WithLabelValues(result)
+	}
+	return p, nil
+}
+
+// Run gathers and pushes the metrics until ctx is done
+func (p *Pusher) Run(ctx context.Context) {
+	p.logger.Info("pushing metrics to remote write endpoint", "url", p.cfg.URL, "interval", p.cfg.Interval, "queued", p.queue.len())
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		p.sendQueued(ctx)
+	})
+
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		p.collect(ctx)
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collect gathers the metrics and queues them as a request
+func (p *Pusher) collect(ctx context.Context) {
+	mfs, _ := p.gather(ctx)
+	if len(mfs) == 0 {
+		return
+	}
+	req, series := encodeWriteRequest(mfs, p.now().UnixMilli())
+	dropped, err := p.queue.push(s2.EncodeSnappy(nil, req))
+	if err != nil {
+		p.logger.Error("queueing remote write request failed", "error", err)
+		p.requests.WithLabelValues("dropped").Inc()
+		return
+	}
+	if dropped > 0 {
+		p.logger.Warn("remote write queue is full, dropped the oldest requests", "dropped", dropped, "capacity", p.cfg.Queue.Capacity)
+		p.requests.WithLabelValues("dropped").Add(float64(dropped))
+	}
+	p.logger.Debug("queued remote write request", "series", series, "queued", p.queue.len())
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// sendQueued sends the queued requests in order whenever a request is queued, until ctx is done
+func (p *Pusher) sendQueued(ctx context.Context) {
+	backoff := p.cfg.MinBackoff
+	for {
+		seq, req, found, err := p.queue.peek()
+		switch {
+		case err != nil:
+			p.logger.Error("reading queued remote write request failed, dropping it", "error", err)
+			p.drop(seq)
+			continue
+		case !found:
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.notify:
+				continue
+			}
+		}
+
+		err = p.send(ctx, req)
+		var httpErr *httpError
+		switch {
+		case err == nil:
+			p.requests.WithLabelValues("sent").Inc()
+			p.lastSuccess.Set(float64(p.now().UnixNano()) / 1e9)
+			backoff = p.cfg.MinBackoff
+			if _, err := p.queue.pop(seq); err != nil {
+				p.logger.Error("removing sent remote write request from the queue failed", "error", err)
+			}
+		case ctx.Err() != nil:
+			return
+		case errors.As(err, &httpErr) && !httpErr.recoverable():
+			p.logger.Error("remote write request rejected, dropping it", "error", err)
+			p.drop(seq)
+		default:
+			p.logger.Warn("remote write request failed, retrying", "error", err, "backoff", backoff, "queued", p.queue.len())
+			p.requests.WithLabelValues("retried").Inc()
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, p.cfg.MaxBackoff)
+		}
+	}
+}
+
+// drop removes the request from the queue, unless pushing a new request already dropped it
+func (p *Pusher) drop(seq uint64) {
+	removed, err := p.queue.pop(seq)
+	if err != nil {
+		p.logger.Error("removing dropped remote write request from the queue failed", "error", err)
+	}
+	if removed {
+		p.requests.WithLabelValues("dropped").Inc()
+	}
+}
+
+type httpError struct {
+	code int
+	body string
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("server returned HTTP status %d: %s", e.code, e.body)
+}
+
+func (e *httpError) recoverable() bool {
+	return e.code >= 500 || e.code == http.StatusTooManyRequests
+}
+
+func (p *Pusher) send(ctx context.Context, req []byte) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.URL, bytes.NewReader(req))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Encoding", "snappy")
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("User-Agent", userAgent)
+	httpReq.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 != 2 {
+		return &httpError{code: resp.StatusCode, body: string(bytes.TrimSpace(body))}
+	}
+	return nil
+}
+
+// Describe implements prometheus.Collector for the syslogng_exporter_remote_write_* metrics
+func (p *Pusher) Describe(ch chan<- *prometheus.Desc) {
+	p.requests.Describe(ch)
+	p.queueLength.Describe(ch)
+	p.lastSuccess.Describe(ch)
+}
+
+// Collect implements prometheus.Collector for the syslogng_exporter_remote_write_* metrics
+func (p *Pusher) Collect(ch chan<- prometheus.Metric) {
+	p.requests.Collect(ch)
+	p.queueLength.Collect(ch)
+	p.lastSuccess.Collect(ch)
+}
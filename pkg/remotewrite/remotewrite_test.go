@@ -0,0 +1,267 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remotewrite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/pkg/httpclient"
+
+	"github.com/axoflow/axosyslog-metrics-exporter/internal/metrictest"
+)
+
+const input = `# HELP syslogng_output_events_total Number of messages delivered, dropped or queued by the destination.
+# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{id="d1",result="delivered"} 10
+# TYPE syslogng_up gauge
+syslogng_up 1
+# TYPE syslogng_delay_seconds histogram
+syslogng_delay_seconds_bucket{le="1"} 2
+syslogng_delay_seconds_sum 1.5
+syslogng_delay_seconds_count 3
+`
+
+// decodeWriteRequest decodes the series of a remote write request as lines of name{labels} value @timestamp, and
+// the metadata as lines of # name type help
+func decodeWriteRequest(t *testing.T, req []byte) []string {
+	var lines []string
+	fields := func(b []byte, fn func(num protowire.Number, typ protowire.Type, b []byte) int) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			require.GreaterOrEqual(t, n, 0)
+			b = b[n:]
+			n = fn(num, typ, b)
+			if n == 0 {
+				n = protowire.ConsumeFieldValue(num, typ, b)
+			}
+			require.GreaterOrEqual(t, n, 0)
+			b = b[n:]
+		}
+	}
+	fields(req, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		msg, n := protowire.ConsumeBytes(b)
+		switch num {
+		case writeRequestTimeseries:
+			var labels []string
+			var sample string
+			fields(msg, func(num protowire.Number, _ protowire.Type, b []byte) int {
+				msg, n := protowire.ConsumeBytes(b)
+				var parts []string
+				fields(msg, func(num protowire.Number, typ protowire.Type, b []byte) int {
+					switch typ {
+					case protowire.BytesType:
+						v, n := protowire.ConsumeString(b)
+						parts = append(parts, v)
+						return n
+					case protowire.Fixed64Type:
+						v, n := protowire.ConsumeFixed64(b)
+						parts = append(parts, fmt.Sprint(math.Float64frombits(v)))
+						return n
+					default:
+						v, n := protowire.ConsumeVarint(b)
+						parts = append(parts, fmt.Sprint(v))
+						return n
+					}
+				})
+				if num == timeSeriesLabels {
+					labels = append(labels, parts[0]+"="+parts[1])
+				} else {
+					sample = parts[0] + " @" + parts[1]
+				}
+				return n
+			})
+			lines = append(lines, "{"+strings.Join(labels, ",")+"} "+sample)
+		case writeRequestMetadata:
+			var parts []string
+			fields(msg, func(_ protowire.Number, typ protowire.Type, b []byte) int {
+				if typ == protowire.VarintType {
+					v, n := protowire.ConsumeVarint(b)
+					parts = append(parts, fmt.Sprint(v))
+					return n
+				}
+				v, n := protowire.ConsumeString(b)
+				parts = append(parts, v)
+				return n
+			})
+			lines = append(lines, "# "+strings.Join(parts, " "))
+		}
+		return n
+	})
+	return lines
+}
+
+func TestEncodeWriteRequest(t *testing.T) {
+	req, series := encodeWriteRequest(metrictest.Parse(t, input), 1000)
+	assert.Equal(t, 6, series)
+	assert.Equal(t, []string{
+		`{__name__=syslogng_output_events_total,id=d1,result=delivered} 10 @1000`,
+		`{__name__=syslogng_up} 1 @1000`,
+		`{__name__=syslogng_delay_seconds_bucket,le=1} 2 @1000`,
+		`{__name__=syslogng_delay_seconds_bucket,le=+Inf} 3 @1000`,
+		`{__name__=syslogng_delay_seconds_sum} 1.5 @1000`,
+		`{__name__=syslogng_delay_seconds_count} 3 @1000`,
+		`# 1 syslogng_output_events_total Number of messages delivered, dropped or queued by the destination.`,
+		`# 2 syslogng_up `,
+		`# 3 syslogng_delay_seconds `,
+	}, decodeWriteRequest(t, req))
+}
+
+type receiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	received [][]string
+	headers  http.Header
+}
+
+func (rcv *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.headers = r.Header.Clone()
+	status := http.StatusNoContent
+	if len(rcv.statuses) > 0 {
+		status, rcv.statuses = rcv.statuses[0], rcv.statuses[1:]
+	}
+	if status/100 == 2 {
+		body, _ := io.ReadAll(r.Body)
+		req, err := s2.Decode(nil, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var series []string
+		for _, line := range decodeWriteRequest(rcv.t, req) {
+			if !strings.HasPrefix(line, "#") {
+				series = append(series, line)
+			}
+		}
+		rcv.received = append(rcv.received, series)
+	}
+	w.WriteHeader(status)
+}
+
+func TestPusher(t *testing.T) {
+	rcv := &receiver{t: t, statuses: []int{http.StatusServiceUnavailable, http.StatusBadRequest}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	var gathered int
+	pusher, err := New(Config{
+		URL:        srv.URL,
+		Interval:   10 * time.Millisecond,
+		MinBackoff: time.Millisecond,
+		HTTP:       httpclient.Config{BearerToken: "secret"},
+		Queue:      QueueConfig{Directory: filepath.Join(t.TempDir(), "queue")},
+	}, func(context.Context) ([]*io_prometheus_client.MetricFamily, error) {
+		gathered++
+		return metrictest.Parse(t, input)[1:2], nil
+	}, WithLogger(slog.New(slog.DiscardHandler)))
+	require.NoError(t, err)
+	pusher.now = func() time.Time { return time.UnixMilli(2000) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		pusher.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return testutil.ToFloat64(pusher.requests.WithLabelValues("sent")) >= 2 }, 5*time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	assert.Equal(t, []string{`{__name__=syslogng_up} 1 @2000`}, rcv.received[0])
+	assert.Equal(t, "Bearer secret", rcv.headers.Get("Authorization"))
+	assert.Equal(t, "snappy", rcv.headers.Get("Content-Encoding"))
+	assert.Equal(t, "0.1.0", rcv.headers.Get("X-Prometheus-Remote-Write-Version"))
+
+	// the first request was retried after 503, the second one was dropped after 400
+	assert.Equal(t, 1.0, testutil.ToFloat64(pusher.requests.WithLabelValues("retried")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(pusher.requests.WithLabelValues("dropped")))
+	assert.Greater(t, gathered, 2)
+}
+
+func TestQueues(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "queue")
+	disk, err := newDiskQueue(dir, 2)
+	require.NoError(t, err)
+
+	for name, q := range map[string]queue{"memory": newMemoryQueue(2), "disk": disk} {
+		t.Run(name, func(t *testing.T) {
+			_, _, found, err := q.peek()
+			require.NoError(t, err)
+			assert.False(t, found)
+
+			for i, req := range []string{"a", "b", "c"} {
+				dropped, err := q.push([]byte(req))
+				require.NoError(t, err)
+				assert.Equal(t, max(i-1, 0), dropped)
+			}
+			assert.Equal(t, 2, q.len())
+			seq, req, found, err := q.peek()
+			require.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, "b", string(req))
+
+			// the request being sent is dropped by a push, popping it keeps the newer requests
+			_, err = q.push([]byte("d"))
+			require.NoError(t, err)
+			removed, err := q.pop(seq)
+			require.NoError(t, err)
+			assert.False(t, removed)
+			assert.Equal(t, 2, q.len())
+
+			seq, req, _, err = q.peek()
+			require.NoError(t, err)
+			assert.Equal(t, "c", string(req))
+			removed, err = q.pop(seq)
+			require.NoError(t, err)
+			assert.True(t, removed)
+			assert.Equal(t, 1, q.len())
+		})
+	}
+
+	// the disk queue is restored
+	disk, err = newDiskQueue(dir, 2)
+	require.NoError(t, err)
+	_, req, _, err := disk.peek()
+	require.NoError(t, err)
+	assert.Equal(t, "d", string(req))
+	_, err = disk.push([]byte("e"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, disk.len())
+	entries, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "no leftover files")
+}
@@ -90,6 +90,19 @@ func main() {
 				}
 			},
 		},
+		{
+			Args: []string{"stats", "prometheus", "--with-legacy"},
+			Func: func() {
+				metrics, err := syslogngctl.NewController(ctl.ControlChannel, syslogngctl.WithLegacyStats(true)).StatsPrometheus(context.Background())
+				if err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "An error occurred while querying prometheus stats: %s\n", err.Error())
+					os.Exit(2)
+				}
+				for _, mf := range metrics {
+					_, _ = expfmt.MetricFamilyToText(os.Stdout, mf)
+				}
+			},
+		},
 		{
 			Args: []string{"stats", "--remove-orphans"},
 			Func: func() {
@@ -16,6 +16,7 @@ package syslogngctl
 
 import (
 	"context"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -26,18 +27,104 @@ import (
 //
 // Reference for available commands in syslog-ng-ctl's source code: https://github.com/syslog-ng/syslog-ng/blob/0e7c762c704efbda0ae10b61c35700ef0bdbb9c1/syslog-ng-ctl/syslog-ng-ctl.c#L111
 type Controller struct {
-	ControlChannel      ControlChannel
-	mu                  sync.Mutex
-	lastMetricQueryTime time.Time
+	ControlChannel  ControlChannel
+	createdAt       time.Time
+	withLegacyStats bool
+	lenientParsing  bool
+	redactions      []LabelRedaction
+	statsTrace      func(StatsTrace)
+	catalog         *MetricCatalog
+	logger          *slog.Logger
+
+	collisionsMu sync.Mutex
+	// collisions are the metric families whose series merged by the redaction were logged
+	collisions map[string]bool
+
+	watermarksMu sync.Mutex
+	// watermarks holds the time of the last StatsPrometheus query of each scraper
+	watermarks map[string]time.Time
+	scraperTTL time.Duration
+}
+
+// DefaultScraperTTL is the time after which the event delay watermark of an inactive scraper is forgotten
+const DefaultScraperTTL = 10 * time.Minute
+
+// ControllerOption is an option for NewController
+type ControllerOption func(*Controller)
+
+// WithLegacyStats makes StatsPrometheus request the legacy counters as well (STATS PROMETHEUS WITH_LEGACY)
+func WithLegacyStats(enabled bool) ControllerOption {
+	return func(c *Controller) {
+		c.withLegacyStats = enabled
+	}
+}
+
+// WithLenientParsing makes StatsPrometheus skip the malformed lines and metric families of the response instead of
+// failing. The valid metric families are returned along with a *PartialResponseError listing the skipped items.
+func WithLenientParsing(enabled bool) ControllerOption {
+	return func(c *Controller) {
+		c.lenientParsing = enabled
+	}
+}
+
+// WithLabelRedaction makes StatsPrometheus redact the values of the labels, e.g. to hide host names or credentials
+// in driver_instance. The redactions are applied in order.
+func WithLabelRedaction(redactions ...LabelRedaction) ControllerOption {
+	return func(c *Controller) {
+		c.redactions = append(c.redactions, redactions...)
+	}
 }
 
-func NewController(controlChannel ControlChannel) *Controller {
-	return &Controller{
-		ControlChannel:      controlChannel,
-		lastMetricQueryTime: time.Now(),
+// WithLogger sets the logger of the controller, e.g. for the series merged by the redaction (default: slog.Default)
+func WithLogger(logger *slog.Logger) ControllerOption {
+	return func(c *Controller) {
+		c.logger = logger
 	}
 }
 
+// WithStatsTrace sets a function called with the response size and processing times of every StatsPrometheus query
+// that received a response, e.g. to instrument the caller
+func WithStatsTrace(fn func(StatsTrace)) ControllerOption {
+	return func(c *Controller) {
+		c.statsTrace = fn
+	}
+}
+
+// WithMetricCatalog sets the catalog used to annotate the metrics returned by StatsPrometheus (default: DefaultMetricCatalog)
+func WithMetricCatalog(catalog *MetricCatalog) ControllerOption {
+	return func(c *Controller) {
+		c.catalog = catalog
+	}
+}
+
+// WithScraperTTL sets the time after which the event delay watermark of an inactive scraper is forgotten (default: DefaultScraperTTL)
+func WithScraperTTL(ttl time.Duration) ControllerOption {
+	return func(c *Controller) {
+		c.scraperTTL = ttl
+	}
+}
+
+func NewController(controlChannel ControlChannel, opts ...ControllerOption) *Controller {
+	c := &Controller{
+		ControlChannel: controlChannel,
+		createdAt:      time.Now(),
+		catalog:        defaultMetricCatalog,
+		logger:         slog.Default(),
+		collisions:     make(map[string]bool),
+		watermarks:     make(map[string]time.Time),
+		scraperTTL:     DefaultScraperTTL,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// MetricCatalog returns the catalog used to annotate the metrics returned by StatsPrometheus
+func (c *Controller) MetricCatalog() *MetricCatalog {
+	return c.catalog
+}
+
 func (c *Controller) GetLicenseInfo(ctx context.Context) (string, error) {
 	return License(ctx, c.ControlChannel)
 }
@@ -66,10 +153,85 @@ func (c *Controller) PreprocessedConfig(ctx context.Context) (string, error) {
 	return PreprocessedConfig(ctx, c.ControlChannel)
 }
 
+func (c *Controller) ConfigID(ctx context.Context) (string, error) {
+	return ConfigID(ctx, c.ControlChannel)
+}
+
 func (c *Controller) StatsPrometheus(ctx context.Context) ([]*io_prometheus_client.MetricFamily, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return StatsPrometheus(ctx, c.ControlChannel, &c.lastMetricQueryTime)
+	return c.StatsPrometheusForScraper(ctx, "")
+}
+
+// StatsPrometheusForScraper works like StatsPrometheus, but keeps track of the already returned event delay samples
+// separately for each scraper. This way every scraper (e.g. Prometheus replicas in HA) receives every sample, instead
+// of the callers stealing samples from each other.
+func (c *Controller) StatsPrometheusForScraper(ctx context.Context, scraper string) ([]*io_prometheus_client.MetricFamily, error) {
+	opts := statsPrometheusOptions{
+		catalog:            c.catalog,
+		withLegacy:         c.withLegacyStats,
+		lenient:            c.lenientParsing,
+		redactions:         c.redactions,
+		redactionCollision: c.logRedactionCollision,
+		trace:              c.statsTrace,
+	}
+	lastMetricQueryTime := c.watermark(scraper)
+	mfs, err := statsPrometheus(ctx, c.ControlChannel, opts, &lastMetricQueryTime)
+	c.setWatermark(scraper, lastMetricQueryTime)
+	return mfs, err
+}
+
+// StatsPrometheusAllSamples works like StatsPrometheus, but returns the last event delay sample of every destination
+// regardless of the earlier queries. It is meant for callers sharing the result between scrapers and tracking the
+// delivered samples themselves by their timestamps.
+func (c *Controller) StatsPrometheusAllSamples(ctx context.Context) ([]*io_prometheus_client.MetricFamily, error) {
+	opts := statsPrometheusOptions{
+		catalog:            c.catalog,
+		withLegacy:         c.withLegacyStats,
+		lenient:            c.lenientParsing,
+		redactions:         c.redactions,
+		redactionCollision: c.logRedactionCollision,
+		trace:              c.statsTrace,
+	}
+	var lastMetricQueryTime time.Time
+	return statsPrometheus(ctx, c.ControlChannel, opts, &lastMetricQueryTime)
+}
+
+// logRedactionCollision warns about the series merged by the redaction, once for each metric family
+func (c *Controller) logRedactionCollision(family string, merged int) {
+	c.collisionsMu.Lock()
+	defer c.collisionsMu.Unlock()
+	if c.collisions[family] {
+		return
+	}
+	c.collisions[family] = true
+	c.logger.Warn("redaction made the labels of series identical, they are merged", "family", family, "merged", merged)
+}
+
+// watermark returns the time of the last query of the scraper, and forgets the scrapers inactive for longer than the TTL
+func (c *Controller) watermark(scraper string) time.Time {
+	c.watermarksMu.Lock()
+	defer c.watermarksMu.Unlock()
+
+	now := time.Now()
+	for id, lastQuery := range c.watermarks {
+		if now.Sub(lastQuery) > c.scraperTTL {
+			delete(c.watermarks, id)
+		}
+	}
+
+	if lastQuery, ok := c.watermarks[scraper]; ok {
+		return lastQuery
+	}
+	return c.createdAt
+}
+
+func (c *Controller) setWatermark(scraper string, lastQuery time.Time) {
+	c.watermarksMu.Lock()
+	defer c.watermarksMu.Unlock()
+
+	// concurrent queries of the same scraper may finish out of order
+	if lastQuery.After(c.watermarks[scraper]) {
+		c.watermarks[scraper] = lastQuery
+	}
 }
 
 func (c *Controller) StatsRemoveOrphans(ctx context.Context) error {
@@ -16,9 +16,14 @@ package syslogngctl
 
 import (
 	"context"
+	"fmt"
+	"slices"
 	"sync"
 	"testing"
+	"time"
 
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -40,3 +45,51 @@ func TestControllerStatsPrometheusConcurrent(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestControllerStatsPrometheusForScraper(t *testing.T) {
+	sampleTime := time.Now().Add(-30 * time.Second)
+	cc := ControlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		age := int(time.Since(sampleTime).Seconds())
+		return "syslogng_output_event_delay_sample_seconds{id=\"d\"} 1\n" +
+			fmt.Sprintf("syslogng_output_event_delay_sample_age_seconds{id=\"d\"} %d\n", age), nil
+	})
+	hasDelaySample := func(ctl *Controller, scraper string) bool {
+		mfs, err := ctl.StatsPrometheusForScraper(context.Background(), scraper)
+		require.NoError(t, err)
+		return slices.ContainsFunc(mfs, func(mf *io_prometheus_client.MetricFamily) bool {
+			return mf.GetName() == "syslogng_output_event_delay_sample_seconds"
+		})
+	}
+
+	ctl := NewController(cc)
+	ctl.createdAt = time.Now().Add(-time.Minute)
+	assert.True(t, hasDelaySample(ctl, "prometheus-0"))
+	assert.False(t, hasDelaySample(ctl, "prometheus-0"), "the same sample should not be returned twice to the same scraper")
+	assert.True(t, hasDelaySample(ctl, "prometheus-1"), "other scrapers should receive the sample as well")
+	assert.False(t, hasDelaySample(ctl, "prometheus-1"))
+
+	ctl = NewController(cc, WithScraperTTL(time.Nanosecond))
+	ctl.createdAt = time.Now().Add(-time.Minute)
+	assert.True(t, hasDelaySample(ctl, "prometheus-0"))
+	assert.True(t, hasDelaySample(ctl, "prometheus-0"), "the watermark of inactive scrapers should expire")
+}
+
+func TestControllerStatsTrace(t *testing.T) {
+	const output = "syslogng_scratch_buffers_count 2\n"
+	var traces []StatsTrace
+	ctl := NewController(
+		ControlChannelFunc(func(_ context.Context, _ string) (string, error) {
+			return output, nil
+		}),
+		WithStatsTrace(func(trace StatsTrace) {
+			traces = append(traces, trace)
+		}),
+	)
+
+	_, err := ctl.StatsPrometheus(context.Background())
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	assert.Equal(t, len(output), traces[0].ResponseSize)
+	assert.Positive(t, traces[0].Parse)
+	assert.GreaterOrEqual(t, traces[0].Transform, time.Duration(0))
+}
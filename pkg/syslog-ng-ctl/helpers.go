@@ -16,6 +16,7 @@ package syslogngctl
 
 import (
 	"context"
+	"crypto/tls"
 	"io"
 	"net"
 )
@@ -26,3 +27,20 @@ func NewUnixDomainSocketControlChannel(socketAddr string) ControlChannel {
 		return d.DialContext(ctx, "unix", socketAddr)
 	})
 }
+
+// NewTCPControlChannel connects to a control socket exposed over TCP, e.g. by socat
+func NewTCPControlChannel(addr string) ControlChannel {
+	return NewReadWriterControlChannel(func(ctx context.Context) (io.ReadWriter, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", addr)
+	})
+}
+
+// NewTLSControlChannel connects to a control socket exposed over TLS, e.g. by stunnel. The server name defaults to
+// the host of addr.
+func NewTLSControlChannel(addr string, cfg *tls.Config) ControlChannel {
+	return NewReadWriterControlChannel(func(ctx context.Context) (io.ReadWriter, error) {
+		d := tls.Dialer{Config: cfg}
+		return d.DialContext(ctx, "tcp", addr)
+	})
+}
@@ -0,0 +1,48 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// metricsdoc generates the metrics reference document from the built-in metric catalog
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	syslogngctl "github.com/axoflow/axosyslog-metrics-exporter/pkg/syslog-ng-ctl"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		_, _ = fmt.Fprintf(os.Stderr, "Usage: %s <output file>\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	var doc bytes.Buffer
+	doc.WriteString("# Metrics reference\n\n")
+	doc.WriteString("<!-- Code generated by internal/metricsdoc. DO NOT EDIT. -->\n\n")
+	doc.WriteString("The metrics below are known to the exporter: they are exported with the listed type, help text and unit.\n")
+	doc.WriteString("Other metric families are typed by their name: `_events_total` families are counters, everything else is a gauge.\n\n")
+	doc.WriteString("| Name | Type | Unit | Help |\n")
+	doc.WriteString("|------|------|------|------|\n")
+	for _, md := range syslogngctl.DefaultMetricCatalog().Metadata() {
+		_, _ = fmt.Fprintf(&doc, "| `%s` | %s | %s | %s |\n", md.Name, strings.ToLower(md.Type.String()), md.Unit, md.Help)
+	}
+
+	if err := os.WriteFile(os.Args[1], doc.Bytes(), 0o644); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "An error occurred while writing the metrics reference: %s\n", err.Error())
+		os.Exit(2)
+	}
+}
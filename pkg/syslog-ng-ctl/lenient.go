@@ -0,0 +1,188 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslogngctl
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+// maxSkippedItems limits the number of items skipped by the lenient parser in a single response,
+// the rest of the response is dropped after that
+const maxSkippedItems = 100
+
+// SkippedItem is a line or a metric family of a response skipped by the lenient parser
+type SkippedItem struct {
+	// Line is the 1-based line number in the response, 0 if the item is not a single line
+	Line int
+	// Family is the name of the metric family, if known
+	Family string
+	Err    error
+}
+
+func (i SkippedItem) String() string {
+	var b strings.Builder
+	if i.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", i.Line)
+	}
+	if i.Family != "" {
+		fmt.Fprintf(&b, "metric family %q: ", i.Family)
+	}
+	b.WriteString(i.Err.Error())
+	return b.String()
+}
+
+// PartialResponseError is returned in lenient mode along with the valid metric families of the response,
+// when some lines or metric families had to be skipped
+type PartialResponseError struct {
+	Skipped []SkippedItem
+}
+
+func (e *PartialResponseError) Error() string {
+	if len(e.Skipped) == 1 {
+		return fmt.Sprintf("skipped 1 malformed item of the response: %s", e.Skipped[0])
+	}
+	return fmt.Sprintf("skipped %d malformed items of the response, first: %s", len(e.Skipped), e.Skipped[0])
+}
+
+func (e *PartialResponseError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Skipped))
+	for _, item := range e.Skipped {
+		errs = append(errs, item.Err)
+	}
+	return errs
+}
+
+func partialResponseError(skipped []SkippedItem) error {
+	if len(skipped) == 0 {
+		return nil
+	}
+	return &PartialResponseError{Skipped: skipped}
+}
+
+// parseMetricFamiliesLenient parses the text exposition format block by block, a block being the consecutive lines
+// of a metric family. The lines the parser rejects are dropped from their block until the rest of the block can be
+// parsed, so that a malformed line costs parsing its block again, not the whole response.
+func parseMetricFamiliesLenient(text string) (map[string]*io_prometheus_client.MetricFamily, []SkippedItem) {
+	res := make(map[string]*io_prometheus_client.MetricFamily)
+	var skipped []SkippedItem
+	for _, b := range familyBlocks(strings.SplitAfter(text, "\n")) {
+		var mfs map[string]*io_prometheus_client.MetricFamily
+		mfs, skipped = b.parse(skipped)
+		for name, mf := range mfs {
+			prev, ok := res[name]
+			switch {
+			case !ok:
+				res[name] = mf
+			case prev.GetType() != mf.GetType():
+				skipped = append(skipped, SkippedItem{
+					Line:   b.lineNumbers[0],
+					Family: name,
+					Err:    fmt.Errorf("metric family %s appears again with type %s instead of %s", name, mf.GetType(), prev.GetType()),
+				})
+			default:
+				prev.Metric = append(prev.Metric, mf.Metric...)
+			}
+		}
+		if len(skipped) > maxSkippedItems {
+			// the rest of the response is dropped
+			break
+		}
+	}
+	return res, skipped
+}
+
+// familyBlock is a block of consecutive lines of the text exposition format
+type familyBlock struct {
+	lines []string
+	// lineNumbers are the 1-based line numbers of the lines in the response
+	lineNumbers []int
+}
+
+// familyBlocks splits the lines of the text exposition format into blocks of the lines of a single metric family
+func familyBlocks(lines []string) []*familyBlock {
+	var blocks []*familyBlock
+	var current *familyBlock
+	var family, typ string
+	for i, line := range lines {
+		name := metricNameOfLine(line)
+		fields := strings.Fields(line)
+		comment := len(fields) > 0 && fields[0] == "#"
+		sameFamily := name == family ||
+			(!comment && (typ == "histogram" || typ == "summary") && slices.Contains([]string{family + "_bucket", family + "_sum", family + "_count"}, name))
+		if current == nil || (name != "" && !sameFamily) {
+			current = &familyBlock{}
+			blocks = append(blocks, current)
+			family, typ = name, ""
+		}
+		if comment && len(fields) >= 4 && fields[1] == "TYPE" {
+			typ = fields[3]
+		}
+		current.lines = append(current.lines, line)
+		current.lineNumbers = append(current.lineNumbers, i+1)
+	}
+	return blocks
+}
+
+// parse parses the block, dropping the lines the parser rejects, and appends them to the skipped items. The rest of
+// the block is dropped if it cannot be attributed to a single line, or the maximum number of skipped items is reached.
+func (b *familyBlock) parse(skipped []SkippedItem) (map[string]*io_prometheus_client.MetricFamily, []SkippedItem) {
+	lines, lineNumbers := b.lines, b.lineNumbers
+	for {
+		parser := expfmt.NewTextParser(model.UTF8Validation)
+		mfs, err := parser.TextToMetricFamilies(strings.NewReader(strings.Join(lines, "")))
+		if err == nil {
+			return mfs, skipped
+		}
+
+		var parseErr expfmt.ParseError
+		if !errors.As(err, &parseErr) || parseErr.Line < 1 || parseErr.Line > len(lines) || len(skipped) >= maxSkippedItems {
+			// the error cannot be attributed to a single line, keep what could be parsed before it
+			return mfs, append(skipped, SkippedItem{Err: err})
+		}
+
+		i := parseErr.Line - 1
+		parseErr.Line = lineNumbers[i]
+		skipped = append(skipped, SkippedItem{
+			Line:   lineNumbers[i],
+			Family: metricNameOfLine(lines[i]),
+			Err:    parseErr,
+		})
+		lines = slices.Delete(lines, i, i+1)
+		lineNumbers = slices.Delete(lineNumbers, i, i+1)
+	}
+}
+
+// metricNameOfLine returns the metric name of a sample, HELP or TYPE line of the text exposition format
+func metricNameOfLine(line string) string {
+	line = strings.TrimSpace(line)
+	if rest, ok := strings.CutPrefix(line, "#"); ok {
+		fields := strings.Fields(rest)
+		if len(fields) >= 2 && (fields[0] == "HELP" || fields[0] == "TYPE") {
+			return fields[1]
+		}
+		return ""
+	}
+	if i := strings.IndexAny(line, "{ \t"); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
@@ -0,0 +1,125 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslogngctl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsPrometheusLenient(t *testing.T) {
+	const output = `syslogng_scratch_buffers_count 2
+syslogng_input_events_total{id="s_src"} 1x
+syslogng_output_events_total{id="d_dest",result="delivered"} 3
+syslogng_socket_connections{id="s_net" 5
+syslogng_output_events_total{id="d_dest",result="dropped"} 0
+`
+	cc := ControlChannelFunc(func(_ context.Context, cmd string) (rsp string, err error) {
+		return output, nil
+	})
+
+	_, err := NewController(cc).StatsPrometheus(context.Background())
+	var parseErr expfmt.ParseError
+	require.ErrorAs(t, err, &parseErr)
+
+	res, err := NewController(cc, WithLenientParsing(true)).StatsPrometheus(context.Background())
+	var partial *PartialResponseError
+	require.ErrorAs(t, err, &partial)
+	require.Len(t, partial.Skipped, 2)
+	assert.Equal(t, 2, partial.Skipped[0].Line)
+	assert.Equal(t, "syslogng_input_events_total", partial.Skipped[0].Family)
+	assert.Equal(t, 4, partial.Skipped[1].Line)
+	assert.Equal(t, "syslogng_socket_connections", partial.Skipped[1].Family)
+	assert.ErrorAs(t, err, &parseErr)
+
+	sortMetricFamilies(res)
+	assert.Equal(t, `# HELP syslogng_output_events_total Number of messages delivered, dropped or queued by the destination.
+# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{id="d_dest",result="delivered"} 3
+syslogng_output_events_total{id="d_dest",result="dropped"} 0
+# HELP syslogng_scratch_buffers_count Number of allocated scratch buffers.
+# TYPE syslogng_scratch_buffers_count gauge
+syslogng_scratch_buffers_count 2
+`, metricFamiliesToText(res))
+}
+
+func TestParseMetricFamiliesLenientBlocks(t *testing.T) {
+	const output = `# TYPE syslogng_output_event_delay_seconds histogram
+syslogng_output_event_delay_seconds_bucket{id="d_dest",le="1"} 1
+syslogng_output_event_delay_seconds_bucket{id="d_dest",le="+Inf"} x
+syslogng_output_event_delay_seconds_bucket{id="d_dest",le="+Inf"} 2
+syslogng_output_event_delay_seconds_sum{id="d_dest"} 3
+syslogng_output_event_delay_seconds_count{id="d_dest"} 2
+syslogng_scratch_buffers_count 2
+# TYPE syslogng_output_event_delay_seconds gauge
+syslogng_output_event_delay_seconds 1
+`
+	mfs, skipped := parseMetricFamiliesLenient(output)
+	require.Len(t, skipped, 2)
+	assert.Equal(t, 3, skipped[0].Line)
+	assert.Equal(t, "syslogng_output_event_delay_seconds_bucket", skipped[0].Family)
+	assert.Equal(t, 8, skipped[1].Line, "a family appearing again with another type is skipped")
+	require.Len(t, mfs, 2)
+	assert.Equal(t, uint64(2), mfs["syslogng_output_event_delay_seconds"].GetMetric()[0].GetHistogram().GetSampleCount())
+	assert.Len(t, mfs["syslogng_output_event_delay_seconds"].GetMetric()[0].GetHistogram().GetBucket(), 2)
+}
+
+func TestStatsPrometheusLenientLegacy(t *testing.T) {
+	const output = `SourceName;SourceId;SourceInstance;State;Type;Number
+filter;ff;;a;matched;2
+filter;ff;;a;not_matched
+dst.network;d_dest#0;tcp,127.0.0.1:5555;a;written;x
+dst.network;d_dest#0;tcp,127.0.0.1:5555;a;dropped;1
+`
+	cc := ControlChannelFunc(func(_ context.Context, cmd string) (rsp string, err error) {
+		return output, nil
+	})
+
+	res, err := NewController(cc).StatsPrometheus(context.Background())
+	assert.Error(t, err)
+	assert.Empty(t, res)
+
+	res, err = NewController(cc, WithLenientParsing(true)).StatsPrometheus(context.Background())
+	var partial *PartialResponseError
+	require.ErrorAs(t, err, &partial)
+	require.Len(t, partial.Skipped, 2)
+	assert.Equal(t, 3, partial.Skipped[0].Line)
+	assert.ErrorAs(t, partial.Skipped[0].Err, new(InvalidStatLine))
+	assert.Equal(t, 4, partial.Skipped[1].Line)
+	assert.Equal(t, "skipped 2 malformed items of the response, first: line 3: "+partial.Skipped[0].Err.Error(), err.Error())
+
+	sortMetricFamilies(res)
+	assert.Equal(t, `# HELP syslogng_filtered_events_total Number of messages matched or not matched by the filter.
+# TYPE syslogng_filtered_events_total counter
+syslogng_filtered_events_total{id="ff",result="matched"} 2
+# HELP syslogng_output_events_total Number of messages delivered, dropped or queued by the destination.
+# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{driver_instance="tcp,127.0.0.1:5555",id="d_dest#0",result="dropped"} 1
+`, metricFamiliesToText(res))
+}
+
+func TestStatsPrometheusLenientValidResponse(t *testing.T) {
+	cc := ControlChannelFunc(func(_ context.Context, cmd string) (rsp string, err error) {
+		return PROMETHEUS_METRICS_OUTPUT, nil
+	})
+
+	res, err := NewController(cc, WithLenientParsing(true)).StatsPrometheus(context.Background())
+	require.NoError(t, err)
+	assert.NotEmpty(t, res)
+}
@@ -0,0 +1,214 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslogngctl
+
+//go:generate go run ./internal/metricsdoc ../../docs/metrics.md
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+)
+
+// MetricMetadata describes a metric family exposed by syslog-ng
+type MetricMetadata struct {
+	Name string
+	// Type is the type of the metric family, untyped families are typed by their name
+	Type io_prometheus_client.MetricType
+	Help string
+	// Unit is the OpenMetrics unit of the metric family, it must be a suffix of the name (without _total)
+	Unit string
+}
+
+// MetricCatalog holds the metadata of the known metric families.
+//
+// StatsPrometheus uses the catalog to set the type, help and unit of the metric families returned by syslog-ng.
+// Families missing from the catalog are typed by their name, see FallbackCount.
+type MetricCatalog struct {
+	mu        sync.RWMutex
+	metadata  map[string]MetricMetadata
+	fallbacks atomic.Uint64
+}
+
+// NewMetricCatalog creates a catalog containing the known AxoSyslog metrics and the additional entries
+func NewMetricCatalog(additional ...MetricMetadata) *MetricCatalog {
+	c := &MetricCatalog{
+		metadata: make(map[string]MetricMetadata, len(knownMetrics)+len(additional)),
+	}
+	c.Add(knownMetrics...)
+	c.Add(additional...)
+	return c
+}
+
+// Add adds entries to the catalog, overriding existing entries with the same name
+func (c *MetricCatalog) Add(metadata ...MetricMetadata) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, md := range metadata {
+		c.metadata[md.Name] = md
+	}
+}
+
+// Lookup returns the metadata of the named metric family
+func (c *MetricCatalog) Lookup(name string) (MetricMetadata, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	md, ok := c.metadata[name]
+	return md, ok
+}
+
+// Metadata returns all entries of the catalog ordered by name
+func (c *MetricCatalog) Metadata() []MetricMetadata {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.SortedFunc(maps.Values(c.metadata), func(a, b MetricMetadata) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// FallbackCount returns the number of untyped metric families that were not found in the catalog
+// and had to be typed based on their name
+func (c *MetricCatalog) FallbackCount() uint64 {
+	return c.fallbacks.Load()
+}
+
+// annotate sets the metadata of a metric family from the catalog.
+// Untyped families are converted to the type in the catalog, or to the type guessed by fallbackType.
+func (c *MetricCatalog) annotate(mf *io_prometheus_client.MetricFamily, fallbackType func(name string) io_prometheus_client.MetricType) {
+	md, ok := c.Lookup(mf.GetName())
+	if ok {
+		if mf.Help == nil && md.Help != "" {
+			mf.Help = new(md.Help)
+		}
+		if mf.Unit == nil && md.Unit != "" {
+			mf.Unit = new(md.Unit)
+		}
+	}
+
+	if mf.GetType() != io_prometheus_client.MetricType_UNTYPED {
+		return
+	}
+
+	typ := md.Type
+	if !ok {
+		c.fallbacks.Add(1)
+		typ = fallbackType(mf.GetName())
+	} else if typ == io_prometheus_client.MetricType_UNTYPED {
+		typ = fallbackType(mf.GetName())
+	}
+	switch typ {
+	case io_prometheus_client.MetricType_COUNTER:
+		untypedToCounter(mf)
+	default:
+		untypedToGauge(mf)
+	}
+}
+
+// ParseMetricType parses the lowercase name of a metric type as used in the exposition formats (counter, gauge, untyped)
+func ParseMetricType(s string) (io_prometheus_client.MetricType, error) {
+	switch s {
+	case "counter":
+		return io_prometheus_client.MetricType_COUNTER, nil
+	case "gauge":
+		return io_prometheus_client.MetricType_GAUGE, nil
+	case "untyped", "unknown":
+		return io_prometheus_client.MetricType_UNTYPED, nil
+	default:
+		return io_prometheus_client.MetricType_UNTYPED, UnsupportedMetricTypeName(s)
+	}
+}
+
+type UnsupportedMetricTypeName string
+
+func (e UnsupportedMetricTypeName) Error() string {
+	return fmt.Sprintf("metric type %q is not supported, use counter or gauge", string(e))
+}
+
+var defaultMetricCatalog = NewMetricCatalog()
+
+// DefaultMetricCatalog returns the catalog used when no other catalog is configured
+func DefaultMetricCatalog() *MetricCatalog {
+	return defaultMetricCatalog
+}
+
+const (
+	counter = io_prometheus_client.MetricType_COUNTER
+	gauge   = io_prometheus_client.MetricType_GAUGE
+)
+
+// knownMetrics lists the metrics of AxoSyslog.
+//
+// Reference: https://axoflow.com/docs/axosyslog-core/chapter-manage-and-monitor/metrics/
+var knownMetrics = []MetricMetadata{
+	// global
+	{Name: "syslogng_events_allocated_bytes", Type: gauge, Unit: "bytes", Help: "Memory used by the messages currently in flight."},
+	{Name: "syslogng_scratch_buffers_count", Type: gauge, Help: "Number of allocated scratch buffers."},
+	{Name: "syslogng_scratch_buffers_bytes", Type: gauge, Unit: "bytes", Help: "Memory used by the allocated scratch buffers."},
+	{Name: "syslogng_internal_events_queue_length", Type: gauge, Help: "Number of internal() messages waiting to be processed."},
+	{Name: "syslogng_internal_events_queue_capacity", Type: gauge, Help: "Capacity of the queue of internal() messages."},
+	{Name: "syslogng_stats_level", Type: gauge, Help: "Current value of the stats(level()) global option."},
+	{Name: "syslogng_last_config_reload_timestamp_seconds", Type: gauge, Unit: "seconds", Help: "Time of the last configuration reload attempt, in Unix time."},
+	{Name: "syslogng_last_successful_config_reload_timestamp_seconds", Type: gauge, Unit: "seconds", Help: "Time of the last successful configuration reload, in Unix time."},
+	{Name: "syslogng_last_config_file_modification_timestamp_seconds", Type: gauge, Unit: "seconds", Help: "Modification time of the configuration file, in Unix time."},
+	{Name: "syslogng_mainloop_io_worker_roundtrip_latency_seconds", Type: gauge, Unit: "seconds", Help: "Time it takes for an I/O worker to return a job to the main thread."},
+
+	// sources
+	{Name: "syslogng_input_events_total", Type: counter, Help: "Number of messages received by the source."},
+	{Name: "syslogng_input_event_bytes_total", Type: counter, Unit: "bytes", Help: "Number of bytes received by the source."},
+	{Name: "syslogng_input_window_capacity", Type: gauge, Help: "Size of the flow-control window of the source."},
+	{Name: "syslogng_input_window_available", Type: gauge, Help: "Number of free slots in the flow-control window of the source."},
+	{Name: "syslogng_input_window_full_total", Type: counter, Help: "Number of times the flow-control window of the source became full."},
+	{Name: "syslogng_socket_connections", Type: gauge, Help: "Number of currently open connections of the source."},
+	{Name: "syslogng_socket_max_connections", Type: gauge, Help: "Maximum number of connections allowed by the source."},
+	{Name: "syslogng_socket_rejected_connections_total", Type: counter, Help: "Number of connections rejected by the source because max-connections() was reached."},
+	{Name: "syslogng_socket_receive_buffer_max_bytes", Type: gauge, Unit: "bytes", Help: "Maximum size of the socket receive buffer."},
+	{Name: "syslogng_socket_receive_buffer_used_bytes", Type: gauge, Unit: "bytes", Help: "Used size of the socket receive buffer."},
+	{Name: "syslogng_socket_receive_dropped_packets_total", Type: counter, Help: "Number of packets dropped by the kernel because the socket receive buffer was full."},
+
+	// processing
+	{Name: "syslogng_filtered_events_total", Type: counter, Help: "Number of messages matched or not matched by the filter."},
+	{Name: "syslogng_parsed_events_total", Type: counter, Help: "Number of messages processed or discarded by the parser."},
+	{Name: "syslogng_tagged_events_total", Type: counter, Help: "Number of messages with the tag."},
+	{Name: "syslogng_classified_events_total", Type: counter, Help: "Number of messages counted by metrics-probe()."},
+	{Name: "syslogng_classified_output_events_total", Type: counter, Help: "Number of messages counted by metrics-probe() by destination."},
+
+	// destinations
+	{Name: "syslogng_output_events_total", Type: counter, Help: "Number of messages delivered, dropped or queued by the destination."},
+	{Name: "syslogng_output_event_bytes_total", Type: counter, Unit: "bytes", Help: "Number of bytes sent by the destination."},
+	{Name: "syslogng_output_event_retries_total", Type: counter, Help: "Number of delivery retries of the destination."},
+	{Name: "syslogng_output_unreachable", Type: gauge, Help: "Whether the destination is unreachable (1) or not (0)."},
+	{Name: "syslogng_output_event_delay_sample_seconds", Type: gauge, Unit: "seconds", Help: "Latency of a sampled message between its reception and its delivery."},
+	{Name: "syslogng_output_event_delay_sample_age_seconds", Type: gauge, Unit: "seconds", Help: "Time elapsed since the last latency sample was taken."},
+	{Name: "syslogng_output_http_requests_total", Type: counter, Help: "Number of HTTP requests sent by the destination by response status code."},
+	{Name: "syslogng_output_grpc_requests_total", Type: counter, Help: "Number of gRPC requests sent by the destination by response status code."},
+	{Name: "syslogng_output_truncated_events_total", Type: counter, Help: "Number of messages truncated by the destination."},
+	{Name: "syslogng_output_truncated_bytes_total", Type: counter, Unit: "bytes", Help: "Number of bytes truncated by the destination."},
+
+	// queues
+	{Name: "syslogng_memory_queue_events", Type: gauge, Help: "Number of messages in the memory queue of the destination."},
+	{Name: "syslogng_memory_queue_memory_usage_bytes", Type: gauge, Unit: "bytes", Help: "Memory used by the messages in the memory queue of the destination."},
+	{Name: "syslogng_memory_queue_capacity", Type: gauge, Help: "Capacity of the memory queue of the destination in messages."},
+	{Name: "syslogng_disk_queue_events", Type: gauge, Help: "Number of messages in the disk-buffer of the destination."},
+	{Name: "syslogng_disk_queue_memory_usage_bytes", Type: gauge, Unit: "bytes", Help: "Memory used by the in-memory parts of the disk-buffer."},
+	{Name: "syslogng_disk_queue_disk_usage_bytes", Type: gauge, Unit: "bytes", Help: "Size of the messages stored in the disk-buffer file."},
+	{Name: "syslogng_disk_queue_disk_allocated_bytes", Type: gauge, Unit: "bytes", Help: "Size of the disk-buffer file."},
+	{Name: "syslogng_disk_queue_capacity_bytes", Type: gauge, Unit: "bytes", Help: "Maximum size of the disk-buffer file."},
+	{Name: "syslogng_disk_queue_dir_available_bytes", Type: gauge, Unit: "bytes", Help: "Free space in the directory of the disk-buffer files."},
+}
@@ -0,0 +1,68 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslogngctl
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKnownMetrics(t *testing.T) {
+	for _, md := range knownMetrics {
+		assert.True(t, strings.HasPrefix(md.Name, "syslogng_"), md.Name)
+		assert.NotEmpty(t, md.Help, md.Name)
+		assert.Contains(t, []io_prometheus_client.MetricType{counter, gauge}, md.Type, md.Name)
+		if md.Type == counter {
+			assert.True(t, strings.HasSuffix(md.Name, "_total"), "counter %s should end with _total", md.Name)
+		}
+		if md.Unit != "" {
+			assert.True(t, strings.HasSuffix(strings.TrimSuffix(md.Name, "_total"), "_"+md.Unit), "unit of %s should be a suffix of its name", md.Name)
+		}
+	}
+}
+
+func TestControllerMetricCatalog(t *testing.T) {
+	catalog := NewMetricCatalog(MetricMetadata{
+		Name: "syslogng_custom_dropped",
+		Type: counter,
+		Help: "Custom help.",
+	})
+	ctl := NewController(ControlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		return "syslogng_custom_dropped{id=\"a\"} 1\n" +
+			"syslogng_socket_receive_dropped_packets_total{id=\"a\"} 2\n" +
+			"syslogng_unknown_family{id=\"a\"} 3\n", nil
+	}), WithMetricCatalog(catalog))
+
+	mfs, err := ctl.StatsPrometheus(context.Background())
+	require.NoError(t, err)
+	sortMetricFamilies(mfs)
+
+	require.Len(t, mfs, 3)
+	assert.Equal(t, "syslogng_custom_dropped", mfs[0].GetName())
+	assert.Equal(t, io_prometheus_client.MetricType_COUNTER, mfs[0].GetType())
+	assert.Equal(t, "Custom help.", mfs[0].GetHelp())
+	assert.Equal(t, "syslogng_socket_receive_dropped_packets_total", mfs[1].GetName())
+	assert.Equal(t, io_prometheus_client.MetricType_COUNTER, mfs[1].GetType())
+	assert.Equal(t, "syslogng_unknown_family", mfs[2].GetName())
+	assert.Equal(t, io_prometheus_client.MetricType_GAUGE, mfs[2].GetType())
+	assert.Nil(t, mfs[2].Help)
+
+	assert.Equal(t, uint64(1), catalog.FallbackCount())
+}
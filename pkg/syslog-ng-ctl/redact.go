@@ -0,0 +1,124 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslogngctl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"iter"
+	"regexp"
+	"slices"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+)
+
+// Redactor rewrites a label value to hide sensitive information
+type Redactor func(value string) string
+
+// LabelRedaction applies a redactor to the values of the labels
+type LabelRedaction struct {
+	Labels []string
+	Redact Redactor
+}
+
+// RegexRedactor replaces the matches of the regex, the replacement can refer to the submatches like regexp.ReplaceAllString
+func RegexRedactor(re *regexp.Regexp, replacement string) Redactor {
+	return func(value string) string {
+		return re.ReplaceAllString(value, replacement)
+	}
+}
+
+// HMACRedactor replaces the value with the hex encoded HMAC-SHA256 of the value, truncated to length characters
+// (if length is positive). The same value always results in the same hash, so series can still be told apart.
+func HMACRedactor(key []byte, length int) Redactor {
+	return func(value string) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(value))
+		sum := hex.EncodeToString(mac.Sum(nil))
+		if length > 0 && length < len(sum) {
+			sum = sum[:length]
+		}
+		return sum
+	}
+}
+
+// TruncateRedactor keeps the first length characters of the value
+func TruncateRedactor(length int) Redactor {
+	return func(value string) string {
+		if runes := []rune(value); len(runes) > length {
+			return string(runes[:length])
+		}
+		return value
+	}
+}
+
+var urlUserinfo = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^/?#@\s]*@`)
+
+// URLUserinfoRedactor removes the user name and password of the URLs in the value,
+// e.g. http,https://user:pass@es:9200 becomes http,https://es:9200
+func URLUserinfoRedactor() Redactor {
+	return RegexRedactor(urlUserinfo, "$1")
+}
+
+// redactLabels applies the redactions to the typed metric families. Series whose labels become identical are merged:
+// counters and gauges (e.g. queue lengths) by summing their values, event delay samples by keeping the latest one,
+// and other series by keeping the first one. The collision function, if set, is called with the name of every family
+// with merged series and the number of series merged into others.
+func redactLabels(mfs iter.Seq[*io_prometheus_client.MetricFamily], redactions []LabelRedaction, collision func(family string, merged int)) {
+	if len(redactions) == 0 {
+		return
+	}
+	for mf := range mfs {
+		for _, m := range mf.Metric {
+			for _, lp := range m.Label {
+				for _, r := range redactions {
+					if slices.Contains(r.Labels, lp.GetName()) {
+						lp.Value = new(r.Redact(lp.GetValue()))
+					}
+				}
+			}
+		}
+		if merged := mergeDuplicateSeries(mf); merged > 0 && collision != nil {
+			collision(mf.GetName(), merged)
+		}
+	}
+}
+
+// mergeDuplicateSeries merges the series with identical labels, and returns the number of series merged into others
+func mergeDuplicateSeries(mf *io_prometheus_client.MetricFamily) int {
+	seen := make(map[string]*io_prometheus_client.Metric, len(mf.Metric))
+	before := len(mf.Metric)
+	mf.Metric = slices.DeleteFunc(mf.Metric, func(m *io_prometheus_client.Metric) bool {
+		key := LabelSetKey(m.Label)
+		first, ok := seen[key]
+		if !ok {
+			seen[key] = m
+			return false
+		}
+		switch {
+		case first.Counter != nil && m.Counter != nil:
+			first.Counter.Value = new(first.Counter.GetValue() + m.Counter.GetValue())
+		case mf.GetName() == "syslogng_output_event_delay_sample_seconds":
+			if m.GetTimestampMs() > first.GetTimestampMs() {
+				first.Gauge, first.TimestampMs = m.Gauge, m.TimestampMs
+			}
+		case first.Gauge != nil && m.Gauge != nil:
+			first.Gauge.Value = new(first.Gauge.GetValue() + m.Gauge.GetValue())
+		}
+		return true
+	})
+	return before - len(mf.Metric)
+}
@@ -0,0 +1,132 @@
+// Copyright © 2026 Axoflow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslogngctl
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactors(t *testing.T) {
+	assert.Equal(t, "http,https://es:9200/_bulk", URLUserinfoRedactor()("http,https://user:secret@es:9200/_bulk"))
+	assert.Equal(t, "/var/log/messages", URLUserinfoRedactor()("/var/log/messages"))
+	assert.Equal(t, "tcp,<ip>:514", RegexRedactor(regexp.MustCompile(`\d+\.\d+\.\d+\.\d+`), "<ip>")("tcp,10.1.2.3:514"))
+	assert.Equal(t, "/var/l", TruncateRedactor(6)("/var/log/messages"))
+	assert.Equal(t, "short", TruncateRedactor(6)("short"))
+
+	hash := HMACRedactor([]byte("key"), 12)
+	assert.Len(t, hash("db01.internal"), 12)
+	assert.Equal(t, hash("db01.internal"), hash("db01.internal"))
+	assert.NotEqual(t, hash("db01.internal"), hash("db02.internal"))
+	assert.NotEqual(t, hash("db01.internal"), HMACRedactor([]byte("other"), 12)("db01.internal"))
+}
+
+func TestStatsPrometheusRedaction(t *testing.T) {
+	redactions := []LabelRedaction{
+		{Labels: []string{"driver_instance"}, Redact: URLUserinfoRedactor()},
+		{Labels: []string{"driver_instance"}, Redact: TruncateRedactor(12)},
+	}
+
+	testCases := map[string]struct {
+		output   string
+		expected string
+	}{
+		"native": {
+			output: `syslogng_output_events_total{id="d_http",driver_instance="http,https://user:secret@es:9200",result="delivered"} 3
+syslogng_output_events_total{id="d_file",driver_instance="/var/log/messages/a",result="delivered"} 5
+syslogng_output_events_total{id="d_file",driver_instance="/var/log/messages/b",result="delivered"} 7
+`,
+			expected: `# HELP syslogng_output_events_total Number of messages delivered, dropped or queued by the destination.
+# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{driver_instance="/var/log/mes",id="d_file",result="delivered"} 12
+syslogng_output_events_total{driver_instance="http,https:/",id="d_http",result="delivered"} 3
+`,
+		},
+		"legacy": {
+			output: `SourceName;SourceId;SourceInstance;State;Type;Number
+dst.http;d_http#0;http,https://user:secret@es:9200;a;written;3
+dst.file;d_file#0;/var/log/messages/a;a;written;5
+`,
+			expected: `# HELP syslogng_output_events_total Number of messages delivered, dropped or queued by the destination.
+# TYPE syslogng_output_events_total counter
+syslogng_output_events_total{driver_instance="/var/log/mes",id="d_file#0",result="delivered"} 5
+syslogng_output_events_total{driver_instance="http,https:/",id="d_http#0",result="delivered"} 3
+`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cc := ControlChannelFunc(func(_ context.Context, _ string) (string, error) {
+				return tc.output, nil
+			})
+			res, err := NewController(cc, WithLabelRedaction(redactions...)).StatsPrometheus(context.Background())
+			require.NoError(t, err)
+			sortMetricFamilies(res)
+			assert.Equal(t, tc.expected, metricFamiliesToText(res))
+		})
+	}
+}
+
+func TestStatsPrometheusRedactionMerge(t *testing.T) {
+	cc := ControlChannelFunc(func(_ context.Context, _ string) (string, error) {
+		// the legacy set repeats the native counter of d_a
+		return `syslogng_output_events_total{id="d_a",url="http://a",result="delivered"} 3
+syslogng_output_events_total{id="d_a",url="http://b",result="delivered"} 5
+syslogng_output_events_total{id="d_a",url="http://a",result="delivered"} 3
+syslogng_output_event_delay_sample_seconds{id="d_a",url="http://a"} 2
+syslogng_output_event_delay_sample_seconds{id="d_a",url="http://b"} 4
+syslogng_output_event_delay_sample_age_seconds{id="d_a",url="http://a"} 0
+syslogng_output_event_delay_sample_age_seconds{id="d_a",url="http://b"} 0
+syslogng_memory_queue_events{id="d_a",url="http://a"} 1
+syslogng_memory_queue_events{id="d_a",url="http://b"} 2
+`, nil
+	})
+	var logs strings.Builder
+	ctl := NewController(cc, WithLegacyStats(true), WithLabelRedaction(LabelRedaction{Labels: []string{"url"}, Redact: TruncateRedactor(4)}),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	ctl.createdAt = time.Now().Add(-time.Minute)
+	res, err := ctl.StatsPrometheus(context.Background())
+	require.NoError(t, err)
+
+	values := make(map[string][]float64)
+	for _, mf := range res {
+		for _, m := range mf.GetMetric() {
+			v := m.GetCounter().GetValue()
+			if m.Gauge != nil {
+				v = m.GetGauge().GetValue()
+			}
+			values[mf.GetName()] = append(values[mf.GetName()], v)
+		}
+	}
+	assert.Equal(t, []float64{8}, values["syslogng_output_events_total"], "the native and legacy copies are not double counted")
+	assert.Equal(t, []float64{2}, values["syslogng_output_event_delay_sample_seconds"], "delay samples are not summed")
+	assert.Equal(t, []float64{0}, values["syslogng_output_event_delay_sample_age_seconds"])
+	assert.Equal(t, []float64{3}, values["syslogng_memory_queue_events"], "gauges are summed")
+	assert.Contains(t, logs.String(), "family=syslogng_memory_queue_events merged=1")
+
+	// collisions are logged once per family
+	logs.Reset()
+	_, err = ctl.StatsPrometheus(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, logs.String())
+}
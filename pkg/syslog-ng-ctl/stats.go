@@ -57,23 +57,33 @@ func (err InvalidStatLine) Error() string {
 const StatsHeader = "SourceName;SourceId;SourceInstance;State;Type;Number"
 
 func parseStats(rsp string) (stats []Stat, errs error) {
+	stats, skipped := parseStatLines(rsp)
+	for _, item := range skipped {
+		errs = errors.Join(errs, item.Err)
+	}
+	return
+}
+
+// parseStatLines parses the response of the STATS command, skipping the malformed lines
+func parseStatLines(rsp string) (stats []Stat, skipped []SkippedItem) {
 	rsp = strings.TrimRight(rsp, "\n") // remove trailing new line
 	lines := strings.Split(rsp, "\n")
 	// TODO: sanity check: match header line
 	lines = lines[1:] // drop header line: SourceName;SourceId;SourceInstance;State;Type;Number
-	for _, line := range lines {
+	for i, line := range lines {
+		lineNumber := i + 2 // 1-based, after the header line
 		fields := strings.Split(line, ";")
 		if len(fields) != 6 {
-			errs = errors.Join(errs, InvalidStatLine(line))
+			skipped = append(skipped, SkippedItem{Line: lineNumber, Err: InvalidStatLine(line)})
 			continue
 		}
 		if len(fields[3]) != 1 {
-			errs = errors.Join(errs, InvalidStatLine(line))
+			skipped = append(skipped, SkippedItem{Line: lineNumber, Err: InvalidStatLine(line)})
 			continue
 		}
 		num, err := strconv.ParseUint(fields[5], 10, 64)
 		if err != nil {
-			errs = errors.Join(errs, err)
+			skipped = append(skipped, SkippedItem{Line: lineNumber, Err: err})
 			continue
 		}
 
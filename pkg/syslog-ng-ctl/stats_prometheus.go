@@ -28,13 +28,16 @@ import (
 	"github.com/prometheus/common/model"
 )
 
-func createMetricsFromLegacyStats(legacyStats string) (map[string]*io_prometheus_client.MetricFamily, error) {
-	var stats []Stat
-	var err error
-
-	stats, err = parseStats(legacyStats)
-	if err != nil {
-		return nil, err
+// createMetricsFromLegacyStats converts the output of the STATS command to metric families.
+// In lenient mode the malformed lines and conflicting metrics are skipped and reported in a PartialResponseError.
+func createMetricsFromLegacyStats(legacyStats string, lenient bool) (map[string]*io_prometheus_client.MetricFamily, error) {
+	stats, skipped := parseStatLines(legacyStats)
+	if len(skipped) > 0 && !lenient {
+		var errs []error
+		for _, item := range skipped {
+			errs = append(errs, item.Err)
+		}
+		return nil, errors.Join(errs...)
 	}
 
 	mfs := make(map[string]*io_prometheus_client.MetricFamily)
@@ -108,8 +111,18 @@ func createMetricsFromLegacyStats(legacyStats string) (map[string]*io_prometheus
 		}
 	}
 
-	err = errors.Join(errs...)
-	return mfs, err
+	if lenient {
+		for _, err := range errs {
+			item := SkippedItem{Err: err}
+			var typeMismatch MetricTypeMismatch
+			if errors.As(err, &typeMismatch) {
+				item.Family = typeMismatch.ActualMetricFamily.GetName()
+			}
+			skipped = append(skipped, item)
+		}
+		return mfs, partialResponseError(skipped)
+	}
+	return mfs, errors.Join(errs...)
 }
 
 func transformEventDelayMetric(delayMetric *io_prometheus_client.MetricFamily, delayMetricAge *io_prometheus_client.MetricFamily, now time.Time, lastMetricQueryTime time.Time, mfs map[string]*io_prometheus_client.MetricFamily) {
@@ -121,14 +134,14 @@ func transformEventDelayMetric(delayMetric *io_prometheus_client.MetricFamily, d
 
 	delayMetricAgeByLabel := make(map[string]*io_prometheus_client.Metric)
 	for _, a := range delayMetricAge.Metric {
-		delayMetricAgeByLabel[fmt.Sprint(a.Label)] = a
+		delayMetricAgeByLabel[LabelSetKey(a.Label)] = a
 	}
 
 	transformedMetric := []*io_prometheus_client.Metric{}
 	for _, m := range delayMetric.Metric {
 		delayMetric := m
 
-		if d, ok := delayMetricAgeByLabel[fmt.Sprint(m.Label)]; ok {
+		if d, ok := delayMetricAgeByLabel[LabelSetKey(m.Label)]; ok {
 			delayMetricAge := int(d.GetGauge().GetValue())
 
 			lastDelaySampleTS := now.Add(time.Duration(-delayMetricAge) * time.Second)
@@ -185,8 +198,53 @@ func sanitizeBuggyFormat(output string) string {
 	return fixedOutput.String()
 }
 
+const (
+	statsPrometheusCommand           = "STATS PROMETHEUS"
+	statsPrometheusWithLegacyCommand = "STATS PROMETHEUS WITH_LEGACY"
+)
+
+// legacyCounterTypes and legacyGaugeTypes list the legacy stats types as they appear in the name suffix of the
+// legacy counters returned by STATS PROMETHEUS WITH_LEGACY.
+var (
+	legacyCounterTypes = []string{"processed", "dropped", "written", "suppressed", "discarded", "matched", "not_matched", "truncated_count", "truncated_bytes"}
+	legacyGaugeTypes   = []string{"queued", "memory_usage", "stamp", "value", "connections", "msg_size_max", "msg_size_avg", "eps_last_1h", "eps_last_24h", "eps_since_start", "batch_size_max", "batch_size_avg"}
+)
+
 func StatsPrometheus(ctx context.Context, cc ControlChannel, lastMetricQueryTime *time.Time) ([]*io_prometheus_client.MetricFamily, error) {
-	rsp, err := cc.SendCommand(ctx, "STATS PROMETHEUS")
+	return statsPrometheus(ctx, cc, statsPrometheusOptions{catalog: defaultMetricCatalog}, lastMetricQueryTime)
+}
+
+// StatsPrometheusWithLegacy works like StatsPrometheus, but asks syslog-ng to include the legacy counters in its output.
+// Series present in both the native and the legacy sets are only returned once.
+func StatsPrometheusWithLegacy(ctx context.Context, cc ControlChannel, lastMetricQueryTime *time.Time) ([]*io_prometheus_client.MetricFamily, error) {
+	return statsPrometheus(ctx, cc, statsPrometheusOptions{catalog: defaultMetricCatalog, withLegacy: true}, lastMetricQueryTime)
+}
+
+type statsPrometheusOptions struct {
+	catalog    *MetricCatalog
+	withLegacy bool
+	lenient    bool
+	redactions []LabelRedaction
+	// redactionCollision is called with the families whose series were merged by the redaction
+	redactionCollision func(family string, merged int)
+	trace              func(StatsTrace)
+}
+
+// StatsTrace describes how the response of syslog-ng was processed by StatsPrometheus
+type StatsTrace struct {
+	ResponseSize int
+	// Parse is the time it took to parse the response into metric families
+	Parse time.Duration
+	// Transform is the time it took to type, annotate and deduplicate the metric families
+	Transform time.Duration
+}
+
+func statsPrometheus(ctx context.Context, cc ControlChannel, opts statsPrometheusOptions, lastMetricQueryTime *time.Time) ([]*io_prometheus_client.MetricFamily, error) {
+	cmd := statsPrometheusCommand
+	if opts.withLegacy {
+		cmd = statsPrometheusWithLegacyCommand
+	}
+	rsp, err := cc.SendCommand(ctx, cmd)
 	if err != nil {
 		return nil, err
 	}
@@ -194,62 +252,143 @@ func StatsPrometheus(ctx context.Context, cc ControlChannel, lastMetricQueryTime
 	now := time.Now()
 	defer func() { *lastMetricQueryTime = now }()
 
+	fallbackType := func(name string) io_prometheus_client.MetricType {
+		switch {
+		case strings.HasSuffix(name, "_events_total"):
+			return io_prometheus_client.MetricType_COUNTER
+		case opts.withLegacy && hasLegacyTypeSuffix(name, legacyCounterTypes):
+			return io_prometheus_client.MetricType_COUNTER
+		default:
+			return io_prometheus_client.MetricType_GAUGE
+		}
+	}
+
+	trace := StatsTrace{ResponseSize: len(rsp)}
+	parseStart := time.Now()
+	var transformStart time.Time
+	if opts.trace != nil {
+		defer func() {
+			trace.Parse = transformStart.Sub(parseStart)
+			trace.Transform = time.Since(transformStart)
+			opts.trace(trace)
+		}()
+	}
+
 	var mfs map[string]*io_prometheus_client.MetricFamily
 	if strings.HasPrefix(rsp, StatsHeader) {
-		mfs, err = createMetricsFromLegacyStats(rsp)
+		mfs, err = createMetricsFromLegacyStats(rsp, opts.lenient)
+		transformStart = time.Now()
+		for _, mf := range mfs {
+			opts.catalog.annotate(mf, fallbackType)
+		}
+		redactLabels(maps.Values(mfs), opts.redactions, opts.redactionCollision)
 		return slices.Collect(maps.Values(mfs)), err
 	}
 
 	rsp = sanitizeBuggyFormat(rsp)
-	parser := expfmt.NewTextParser(model.UTF8Validation)
-	mfs, err = parser.TextToMetricFamilies(strings.NewReader(rsp))
+	if opts.lenient {
+		var skipped []SkippedItem
+		mfs, skipped = parseMetricFamiliesLenient(rsp)
+		err = partialResponseError(skipped)
+	} else {
+		parser := expfmt.NewTextParser(model.UTF8Validation)
+		mfs, err = parser.TextToMetricFamilies(strings.NewReader(rsp))
+	}
+	transformStart = time.Now()
 
 	var delayMetric *io_prometheus_client.MetricFamily
 	var delayMetricAge *io_prometheus_client.MetricFamily
 
 	for _, mf := range mfs {
-		if mf.Type == nil {
-			continue
-		}
-		if *mf.Type != io_prometheus_client.MetricType_UNTYPED {
-			continue
-		}
-		if mf.Name == nil {
-			continue
+		if opts.withLegacy {
+			dedupMetrics(mf)
 		}
 
-		switch {
-		case strings.HasSuffix(*mf.Name, "_events_total"):
-			for _, m := range mf.Metric {
-				m.Counter = &io_prometheus_client.Counter{
-					Value: m.Untyped.Value,
-				}
-				m.Untyped = nil
+		switch mf.GetName() {
+		case "syslogng_output_event_delay_sample_seconds":
+			if mf.GetType() == io_prometheus_client.MetricType_UNTYPED {
+				delayMetric = mf
+				continue
 			}
-			mf.Type = io_prometheus_client.MetricType_COUNTER.Enum()
-		case mf.GetName() == "syslogng_output_event_delay_sample_seconds":
-			delayMetric = mf
-		case mf.GetName() == "syslogng_output_event_delay_sample_age_seconds":
+		case "syslogng_output_event_delay_sample_age_seconds":
 			delayMetricAge = mf
-			fallthrough
-		default:
-			for _, m := range mf.Metric {
-				m.Gauge = &io_prometheus_client.Gauge{
-					Value: m.Untyped.Value,
-				}
-				m.Untyped = nil
-			}
-			mf.Type = io_prometheus_client.MetricType_GAUGE.Enum()
 		}
+
+		opts.catalog.annotate(mf, fallbackType)
 	}
 
 	if delayMetric != nil {
 		transformEventDelayMetric(delayMetric, delayMetricAge, now, *lastMetricQueryTime, mfs)
+		opts.catalog.annotate(delayMetric, fallbackType)
 	}
+	// after deduplication and typing, so that only the counters of distinct series are summed
+	redactLabels(maps.Values(mfs), opts.redactions, opts.redactionCollision)
 
 	return slices.Collect(maps.Values(mfs)), err
 }
 
+func untypedToCounter(mf *io_prometheus_client.MetricFamily) {
+	for _, m := range mf.Metric {
+		m.Counter = &io_prometheus_client.Counter{
+			Value: new(m.GetUntyped().GetValue()),
+		}
+		m.Untyped = nil
+	}
+	mf.Type = io_prometheus_client.MetricType_COUNTER.Enum()
+}
+
+func untypedToGauge(mf *io_prometheus_client.MetricFamily) {
+	for _, m := range mf.Metric {
+		m.Gauge = &io_prometheus_client.Gauge{
+			Value: new(m.GetUntyped().GetValue()),
+		}
+		m.Untyped = nil
+	}
+	mf.Type = io_prometheus_client.MetricType_GAUGE.Enum()
+}
+
+// hasLegacyTypeSuffix reports whether name ends with one of the legacy stats types, e.g. syslogng_dst_file_written.
+// Longer types are matched first, so that "_not_matched" is not mistaken for "_matched".
+func hasLegacyTypeSuffix(name string, types []string) bool {
+	typ, ok := legacyTypeOf(name)
+	return ok && slices.Contains(types, typ)
+}
+
+func legacyTypeOf(name string) (string, bool) {
+	var found string
+	for _, typ := range slices.Concat(legacyCounterTypes, legacyGaugeTypes) {
+		if strings.HasSuffix(name, "_"+typ) && len(typ) > len(found) {
+			found = typ
+		}
+	}
+	return found, found != ""
+}
+
+// dedupMetrics removes series with the same label set from a metric family, keeping the first occurrence.
+// STATS PROMETHEUS WITH_LEGACY may report a counter both in the native and in the legacy set, which the text parser
+// merges into the same family.
+func dedupMetrics(mf *io_prometheus_client.MetricFamily) {
+	seen := make(map[string]struct{}, len(mf.Metric))
+	mf.Metric = slices.DeleteFunc(mf.Metric, func(m *io_prometheus_client.Metric) bool {
+		key := LabelSetKey(m.Label)
+		if _, ok := seen[key]; ok {
+			return true
+		}
+		seen[key] = struct{}{}
+		return false
+	})
+}
+
+// LabelSetKey identifies the label set of a series regardless of the order of its labels
+func LabelSetKey(labels []*io_prometheus_client.LabelPair) string {
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, fmt.Sprintf("%q=%q", l.GetName(), l.GetValue()))
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
 func pushMetric(mfs map[string]*io_prometheus_client.MetricFamily, name string, typ io_prometheus_client.MetricType, labels []*io_prometheus_client.LabelPair, value float64) error {
 	m := &io_prometheus_client.Metric{
 		Label: labels,
@@ -33,6 +33,7 @@ func TestStatsPrometheus(t *testing.T) {
 	expected := []*io_prometheus_client.MetricFamily{
 		{
 			Name: amp("syslogng_events_allocated_bytes"),
+			Help: amp("Memory used by the messages currently in flight."),
 			Type: io_prometheus_client.MetricType_GAUGE.Enum(),
 			Metric: []*io_prometheus_client.Metric{
 				{
@@ -44,6 +45,7 @@ func TestStatsPrometheus(t *testing.T) {
 		},
 		{
 			Name: amp("syslogng_scratch_buffers_bytes"),
+			Help: amp("Memory used by the allocated scratch buffers."),
 			Type: io_prometheus_client.MetricType_GAUGE.Enum(),
 			Metric: []*io_prometheus_client.Metric{
 				{
@@ -55,6 +57,7 @@ func TestStatsPrometheus(t *testing.T) {
 		},
 		{
 			Name: amp("syslogng_scratch_buffers_count"),
+			Help: amp("Number of allocated scratch buffers."),
 			Type: io_prometheus_client.MetricType_GAUGE.Enum(),
 			Metric: []*io_prometheus_client.Metric{
 				{
@@ -66,6 +69,7 @@ func TestStatsPrometheus(t *testing.T) {
 		},
 		{
 			Name: amp("syslogng_filtered_events_total"),
+			Help: amp("Number of messages matched or not matched by the filter."),
 			Type: io_prometheus_client.MetricType_COUNTER.Enum(),
 			Metric: []*io_prometheus_client.Metric{
 				{
@@ -108,6 +112,7 @@ func TestStatsPrometheus(t *testing.T) {
 		},
 		{
 			Name: amp("syslogng_input_events_total"),
+			Help: amp("Number of messages received by the source."),
 			Type: io_prometheus_client.MetricType_COUNTER.Enum(),
 			Metric: []*io_prometheus_client.Metric{
 				{
@@ -133,6 +138,7 @@ func TestStatsPrometheus(t *testing.T) {
 		},
 		{
 			Name: amp("syslogng_output_events_total"),
+			Help: amp("Number of messages delivered, dropped or queued by the destination."),
 			Type: io_prometheus_client.MetricType_COUNTER.Enum(),
 			Metric: []*io_prometheus_client.Metric{
 				{
@@ -199,6 +205,7 @@ func TestStatsPrometheus(t *testing.T) {
 		},
 		{
 			Name: amp("syslogng_parsed_events_total"),
+			Help: amp("Number of messages processed or discarded by the parser."),
 			Type: io_prometheus_client.MetricType_COUNTER.Enum(),
 			Metric: []*io_prometheus_client.Metric{
 				{
@@ -223,6 +230,7 @@ func TestStatsPrometheus(t *testing.T) {
 		},
 		{
 			Name: amp("syslogng_tagged_events_total"),
+			Help: amp("Number of messages with the tag."),
 			Type: io_prometheus_client.MetricType_COUNTER.Enum(),
 			Metric: []*io_prometheus_client.Metric{
 				{
@@ -251,6 +259,7 @@ func TestStatsPrometheus(t *testing.T) {
 	expectedDelayMetrics := []*io_prometheus_client.MetricFamily{
 		{
 			Name: amp("syslogng_output_event_delay_sample_seconds"),
+			Help: amp("Latency of a sampled message between its reception and its delivery."),
 			Type: io_prometheus_client.MetricType_GAUGE.Enum(),
 			Metric: []*io_prometheus_client.Metric{
 				{
@@ -268,6 +277,7 @@ func TestStatsPrometheus(t *testing.T) {
 		},
 		{
 			Name: amp("syslogng_output_event_delay_sample_age_seconds"),
+			Help: amp("Time elapsed since the last latency sample was taken."),
 			Type: io_prometheus_client.MetricType_GAUGE.Enum(),
 			Metric: []*io_prometheus_client.Metric{
 				{
@@ -300,6 +310,7 @@ func TestStatsPrometheus(t *testing.T) {
 	expectedEscapeMetrics := []*io_prometheus_client.MetricFamily{
 		{
 			Name: amp("syslogng_classified_output_events_total"),
+			Help: amp("Number of messages counted by metrics-probe() by destination."),
 			Type: io_prometheus_client.MetricType_COUNTER.Enum(),
 			Metric: []*io_prometheus_client.Metric{
 				{
@@ -374,6 +385,83 @@ func TestStatsPrometheus(t *testing.T) {
 	}
 }
 
+func TestStatsPrometheusWithLegacy(t *testing.T) {
+	expected := []*io_prometheus_client.MetricFamily{
+		{
+			Name: amp("syslogng_output_events_total"),
+			Help: amp("Number of messages delivered, dropped or queued by the destination."),
+			Type: io_prometheus_client.MetricType_COUNTER.Enum(),
+			Metric: []*io_prometheus_client.Metric{
+				{
+					Label: []*io_prometheus_client.LabelPair{
+						newLabel("id", "d_dest#0"),
+						newLabel("result", "delivered"),
+					},
+					Counter: &io_prometheus_client.Counter{
+						Value: amp(3.0),
+					},
+				},
+			},
+		},
+		{
+			Name: amp("syslogng_dst_network_written"),
+			Type: io_prometheus_client.MetricType_COUNTER.Enum(),
+			Metric: []*io_prometheus_client.Metric{
+				{
+					Label: []*io_prometheus_client.LabelPair{
+						newLabel("id", "d_dest#0"),
+						newLabel("instance", "tcp,127.0.0.1:5555"),
+					},
+					Counter: &io_prometheus_client.Counter{
+						Value: amp(3.0),
+					},
+				},
+			},
+		},
+		{
+			Name: amp("syslogng_dst_network_queued"),
+			Type: io_prometheus_client.MetricType_GAUGE.Enum(),
+			Metric: []*io_prometheus_client.Metric{
+				{
+					Label: []*io_prometheus_client.LabelPair{
+						newLabel("id", "d_dest#0"),
+						newLabel("instance", "tcp,127.0.0.1:5555"),
+					},
+					Gauge: &io_prometheus_client.Gauge{
+						Value: amp(1.0),
+					},
+				},
+			},
+		},
+		{
+			Name: amp("syslogng_filter_not_matched"),
+			Type: io_prometheus_client.MetricType_COUNTER.Enum(),
+			Metric: []*io_prometheus_client.Metric{
+				{
+					Label: []*io_prometheus_client.LabelPair{
+						newLabel("id", "ff"),
+					},
+					Counter: &io_prometheus_client.Counter{
+						Value: amp(2.0),
+					},
+				},
+			},
+		},
+	}
+	sortMetricFamilies(expected)
+
+	cc := ControlChannelFunc(func(_ context.Context, cmd string) (rsp string, err error) {
+		require.Equal(t, "STATS PROMETHEUS WITH_LEGACY", cmd)
+		return PROMETHEUS_WITH_LEGACY_METRICS_OUTPUT, nil
+	})
+
+	lastMetricQueryTime := time.Now().Add(-time.Second * 30)
+	res, err := StatsPrometheusWithLegacy(context.Background(), cc, &lastMetricQueryTime)
+	require.NoError(t, err)
+	sortMetricFamilies(res)
+	assert.Equal(t, metricFamiliesToText(expected), metricFamiliesToText(res))
+}
+
 type ControlChannelFunc func(ctx context.Context, cmd string) (rsp string, err error)
 
 func (fn ControlChannelFunc) SendCommand(ctx context.Context, cmd string) (rsp string, err error) {
@@ -495,6 +583,13 @@ syslogng_output_event_delay_sample_age_seconds{driver="http",url="http://localho
 syslogng_output_event_delay_sample_age_seconds{transport="tcp",address="localhost:5555",driver="afsocket",id="#anon-destination0#0"} 31
 `
 
+const PROMETHEUS_WITH_LEGACY_METRICS_OUTPUT = `syslogng_output_events_total{id="d_dest#0",result="delivered"} 3
+syslogng_dst_network_written{id="d_dest#0",instance="tcp,127.0.0.1:5555"} 3
+syslogng_dst_network_queued{id="d_dest#0",instance="tcp,127.0.0.1:5555"} 1
+syslogng_filter_not_matched{id="ff"} 2
+syslogng_output_events_total{result="delivered",id="d_dest#0"} 3
+`
+
 const PROMETHEUS_ESCAPE_METRICS_OUTPUT = `syslogng_classified_output_events_total{app="MSWinEventLog\\t1\\tSecurity\\t921448325\\tFri",source="s_critical_hosts_515"} 1
 syslogng_classified_output_events_total{app="\a\t\n\"\xfa\\",source="s_unescaped_bug"} 1
 `